@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"log"
 	"os"
@@ -26,62 +25,114 @@ const (
 	AdjustToEndOfMonth AdjustDay = iota
 	// Normalize 対応する月に同じ日が存在しないときは time.Time.	Date と同じ方法で正規化します
 	Normalize
+	// ErrorOnInvalidDay 対応する月に同じ日が存在しないときはエラーになります
+	ErrorOnInvalidDay
 )
 
 var version = "0.11.1"
 var splitRegexp = regexp.MustCompile(`\s*=\s*`)
 
+// epochRegexp 1526113800 や @1526113800 のような unix 秒の日時
+var epochRegexp = regexp.MustCompile(`^@?(\d+)$`)
+
 // Dt 日付計算とフォーマット機能をもつ
 type Dt struct {
 	time   time.Time
 	format string
+	// leap time の 1 秒後の UTC のうるう秒 (23:59:60) を表すときは true
+	leap bool
 }
 
 func (dt *Dt) get() time.Time {
 	return dt.time
 }
 
-// AddYear 月を加算. 負値のときは減算.
-func (dt *Dt) AddYear(year int) *Dt {
-	return &Dt{
-		time:   dt.time.AddDate(year, 0, 0),
-		format: dt.format,
-	}
+// AddYear 年を加算. 負値のときは減算. 2/29 の 1 年後のように同じ日が存在しないときは adjust に従います.
+func (dt *Dt) AddYear(year int, adjust AdjustDay) (*Dt, error) {
+	return dt.AddMonth(12*year, adjust)
 }
 
 // AddMonth 月を加算. 負値のときは減算.
-func (dt *Dt) AddMonth(month int, adjust AdjustDay) *Dt {
-	result := &Dt{
-		time:   dt.time.AddDate(0, month, 0),
-		format: dt.format,
-	}
-	if adjust == Normalize {
-		return result
-	}
+func (dt *Dt) AddMonth(month int, adjust AdjustDay) (*Dt, error) {
+	return dt.addDate(month, 0, adjust)
+}
 
+// AddDay 日を加算. 負値のときは減算. 時刻ではなく暦の日を加算するので, 夏時間の切り替えをまたぐと 24 時間にはなりません.
+func (dt *Dt) AddDay(day int) (*Dt, error) {
+	return dt.addDate(0, day, Normalize)
+}
+
+// AddWeek 週を加算. 負値のときは減算.
+func (dt *Dt) AddWeek(week int) (*Dt, error) {
+	return dt.AddDay(7 * week)
+}
+
+// addDate 月と日を加算して時刻はそのままにする. 月の加算で同じ日が存在しないときは adjust に従い,
+// 結果の時刻が夏時間の切り替えで存在しないときや 2 回あるときは dstPolicy に従います.
+func (dt *Dt) addDate(month, day int, adjust AdjustDay) (*Dt, error) {
 	t := dt.time
-	firstDayOfMonth := time.Date(t.Year(), t.Month(), 1, t.Hour(),
-		t.Minute(), t.Second(), t.Nanosecond(), t.Location()).AddDate(0, month, 0)
-	if result.time.Month() == firstDayOfMonth.Month() {
-		return result
+	d := t.Day()
+	if adjust != Normalize {
+		firstDayOfMonth := time.Date(t.Year(), t.Month()+time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		if last := firstDayOfMonth.AddDate(0, 1, -1).Day(); d > last {
+			if adjust == ErrorOnInvalidDay {
+				return dt, fmt.Errorf("'%04d-%02d-%02d' is invalid date.", firstDayOfMonth.Year(), firstDayOfMonth.Month(), d)
+			}
+			d = last
+		}
 	}
 
-	lastDayOfPreviousMonth := firstDayOfMonth.AddDate(0, 1, -1)
+	result, err := localDate(t.Year(), t.Month()+time.Month(month), d+day,
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if err != nil {
+		return dt, err
+	}
 	return &Dt{
-		time:   lastDayOfPreviousMonth,
+		time:   result,
 		format: dt.format,
-	}
+	}, nil
 }
 
-// AddDay 日を加算. 負値のときは減算.
-func (dt *Dt) AddDay(day int) *Dt {
+// AddBusinessDay 営業日を加算. 負値のときは減算. 土日と祝日は営業日に数えません.
+func (dt *Dt) AddBusinessDay(day int) (*Dt, error) {
+	step := 1
+	if day < 0 {
+		step, day = -1, -day
+	}
+
+	// 日付だけを進めて, 最後に時刻を合わせる
+	t := dt.time
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	for day > 0 {
+		date = date.AddDate(0, 0, step)
+		if isBusinessDay(date) {
+			day--
+		}
+	}
+	result, err := localDate(date.Year(), date.Month(), date.Day(),
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if err != nil {
+		return dt, err
+	}
 	return &Dt{
-		time:   dt.time.AddDate(0, 0, day),
+		time:   result,
 		format: dt.format,
+	}, nil
+}
+
+func isBusinessDay(t time.Time) bool {
+	if _, ok := holidayName(t); ok {
+		return false
 	}
+	return isWeekend(t) == false
+}
+
+// isWeekend t が土曜日か日曜日のときは true を返す.
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
 }
 
-// AddHour 時を加算. 負値のときは減算.
+// AddHour 時を加算. 負値のときは減算. 経過時間を加算するので, 夏時間の切り替えをまたぐと時計の時刻は同じだけ進みません.
 func (dt *Dt) AddHour(hour int) *Dt {
 	return &Dt{
 		time:   dt.time.Add(time.Duration(hour) * time.Hour),
@@ -113,43 +164,46 @@ func (dt *Dt) String() string {
 		return fmt.Sprintf("%d", t.Unix())
 	case unixMilliSeconds:
 		return fmt.Sprintf("%d", t.UnixNano()/int64(time.Millisecond))
+	case gpsSeconds:
+		return fmt.Sprintf("%d", int64(toScale(t, dt.leap, ScaleGPS).Sub(gpsEpoch)/time.Second))
 	default:
+		if s, ok, _ := formatID(t, f); ok {
+			return s
+		}
+		if dt.leap && showLeapSecond {
+			return formatLeapSecond(t, f)
+		}
 		return t.Format(f)
 	}
 }
 
-func loadConfig() {
+// formatLeapSecond t の 1 秒後のうるう秒を, t を f で書式化した秒の 59 を 60 にして表す.
+// 秒だけが違う時刻と書式化した結果を比べて, 秒の位置を探します.
+func formatLeapSecond(t time.Time, f string) string {
+	s := t.Format(f)
+	other := t.Add(-18 * time.Second).Format(f)
+	if t.Second() != 59 || len(other) != len(s) {
+		return s
+	}
+	b := []byte(s)
+	for i := 0; i+1 < len(b); i++ {
+		if b[i] == '5' && b[i+1] == '9' && other[i] == '4' && other[i+1] == '1' {
+			b[i], b[i+1] = '6', '0'
+			i++
+		}
+	}
+	return string(b)
+}
+
+// configDir 設定ファイルを置くディレクトリ
+func configDir() (string, error) {
 	configPath := os.Getenv("XDG_CONFIG_HOME")
 	if configPath == "" {
 		configPath = "~/.config"
 	}
 	log.Printf("config path: %s\n", configPath)
 
-	path, err := homedir.Expand(configPath + "/dt/.dt")
-	if err != nil {
-		return
-	}
-
-	f, err := os.Open(path)
-	if err != nil {
-		return
-	}
-	defer f.Close()
-
-	scanner := bufio.NewScanner(f)
-	m := map[string]string{}
-	for scanner.Scan() {
-		k, v := splitFormat(scanner.Text())
-		if k == "" || v == "" {
-			continue
-		}
-		m[k] = v
-	}
-
-	for k, v := range m {
-		log.Printf("custom format: %s => %s\n", k, v)
-		formats[k] = v
-	}
+	return homedir.Expand(configPath + "/dt")
 }
 
 func splitFormat(s string) (string, string) {
@@ -166,6 +220,6 @@ func splitFormat(s string) (string, string) {
 }
 
 func main() {
-	cli := &CLO{outStream: os.Stdout, errStream: os.Stderr}
+	cli := &CLO{inStream: os.Stdin, outStream: os.Stdout, errStream: os.Stderr}
 	os.Exit(cli.Run(os.Args))
 }
@@ -19,10 +19,37 @@ func TestDt_AddYear(t *testing.T) {
 	now := time.Now()
 	dt := &Dt{time: now}
 
-	actual := dt.AddYear(1).get()
+	actual, _ := dt.AddYear(1, Normalize)
 	expect := now.AddDate(1, 0, 0)
-	if actual != expect {
-		t.Errorf("Dt.AddYear() = %v, want %v", actual, expect)
+	if actual.get() != expect {
+		t.Errorf("Dt.AddYear() = %v, want %v", actual.get(), expect)
+	}
+}
+
+func TestDt_AddYear_leapDay(t *testing.T) {
+	params := []struct {
+		addition int
+		adjust   AdjustDay
+		expect   time.Time
+	}{
+		{addition: 1, adjust: Normalize, expect: createTime(2025, 3, 1)},
+		{addition: 1, adjust: AdjustToEndOfMonth, expect: createTime(2025, 2, 28)},
+		{addition: -1, adjust: AdjustToEndOfMonth, expect: createTime(2023, 2, 28)},
+		{addition: 4, adjust: ErrorOnInvalidDay, expect: createTime(2028, 2, 29)},
+	}
+
+	for _, p := range params {
+		dt := &Dt{time: createTime(2024, 2, 29)}
+
+		actual, err := dt.AddYear(p.addition, p.adjust)
+		if err != nil || actual.get() != p.expect {
+			t.Errorf("Dt.AddYear(%d, %v) = %v, %v, want %v", p.addition, p.adjust, actual.get(), err, p.expect)
+		}
+	}
+
+	dt := &Dt{time: createTime(2024, 2, 29)}
+	if _, err := dt.AddYear(1, ErrorOnInvalidDay); err == nil || err.Error() != "'2025-02-29' is invalid date." {
+		t.Errorf("Dt.AddYear(1, ErrorOnInvalidDay) = %v, want error", err)
 	}
 }
 
@@ -36,17 +63,23 @@ func TestDt_AddMonth(t *testing.T) {
 		{initial: createTime(2018, 1, 1), addition: 1, adjust: Normalize, expect: createTime(2018, 2, 1)},
 		{initial: createTime(2018, 1, 31), addition: 3, adjust: Normalize, expect: createTime(2018, 5, 1)},
 		{initial: createTime(2018, 1, 31), addition: 3, adjust: AdjustToEndOfMonth, expect: createTime(2018, 4, 30)},
+		{initial: createTime(2018, 1, 31), addition: 2, adjust: ErrorOnInvalidDay, expect: createTime(2018, 3, 31)},
 	}
 
 	for _, p := range params {
 		dt := &Dt{time: p.initial}
 
-		actual := dt.AddMonth(p.addition, p.adjust).get()
+		actual, err := dt.AddMonth(p.addition, p.adjust)
 		expect := p.expect
-		if actual != expect {
-			t.Errorf("Dt.AddMonth() = %v, want %v", actual, expect)
+		if err != nil || actual.get() != expect {
+			t.Errorf("Dt.AddMonth() = %v, %v, want %v", actual.get(), err, expect)
 		}
 	}
+
+	dt := &Dt{time: createTime(2018, 1, 31)}
+	if _, err := dt.AddMonth(1, ErrorOnInvalidDay); err == nil {
+		t.Errorf("Dt.AddMonth(1, ErrorOnInvalidDay) = nil, want error")
+	}
 }
 
 func createTime(year int, month time.Month, day int) time.Time {
@@ -57,7 +90,11 @@ func TestDt_AddDay(t *testing.T) {
 	now := time.Now()
 	dt := &Dt{time: now}
 
-	actual := dt.AddDay(1).get()
+	result, err := dt.AddDay(1)
+	if err != nil {
+		t.Fatalf("Dt.AddDay() error = %v", err)
+	}
+	actual := result.get()
 	expect := now.AddDate(0, 0, 1)
 	if actual != expect {
 		t.Errorf("Dt.AddDay() = %v, want %v", actual, expect)
@@ -115,3 +152,44 @@ func TestSplitFormat(t *testing.T) {
 		}
 	}
 }
+
+func TestDt_AddWeek(t *testing.T) {
+	dt := &Dt{time: createTime(2018, 5, 12)}
+
+	result, err := dt.AddWeek(2)
+	if err != nil {
+		t.Fatalf("Dt.AddWeek() error = %v", err)
+	}
+	actual := result.get()
+	expect := createTime(2018, 5, 26)
+	if actual != expect {
+		t.Errorf("Dt.AddWeek() = %v, want %v", actual, expect)
+	}
+}
+
+func TestDt_AddBusinessDay(t *testing.T) {
+	params := []struct {
+		initial  time.Time
+		addition int
+		expect   time.Time
+	}{
+		{initial: createTime(2018, 5, 11), addition: 1, expect: createTime(2018, 5, 14)},
+		{initial: createTime(2018, 5, 12), addition: 1, expect: createTime(2018, 5, 14)},
+		{initial: createTime(2018, 5, 14), addition: -1, expect: createTime(2018, 5, 11)},
+		{initial: createTime(2018, 5, 14), addition: 5, expect: createTime(2018, 5, 21)},
+		{initial: createTime(2018, 5, 12), addition: 0, expect: createTime(2018, 5, 12)},
+	}
+
+	for _, p := range params {
+		dt := &Dt{time: p.initial}
+
+		result, err := dt.AddBusinessDay(p.addition)
+		if err != nil {
+			t.Fatalf("Dt.AddBusinessDay(%d) error = %v", p.addition, err)
+		}
+		actual := result.get()
+		if actual != p.expect {
+			t.Errorf("Dt.AddBusinessDay(%d) = %v, want %v", p.addition, actual, p.expect)
+		}
+	}
+}
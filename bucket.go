@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+func bucketCommand() cli.Command {
+	return cli.Command{
+		Name:      "bucket",
+		Usage:     "日時を区切りごとに数えます",
+		UsageText: AppName + " bucket [options]",
+		Description: `標準入力の日時を --size の区切りに切り捨て, 区切りの始まりと件数を出力します.
+   区切りは @start と同じ方法で決まります. 件数のない区切りは 0 として出力します.
+   -f, -p, -i を省略したときは 1 行をひとつの日時として読みます.`,
+		HideHelp: true,
+		Flags: commandFlags(append(extractFlags(),
+			cli.StringFlag{
+				Name:  "size, s",
+				Value: "1h",
+				Usage: "区切りの大きさを 5m や 1D のように指定します (Y, M, W, D, h, m, s)",
+			},
+			cli.BoolFlag{
+				Name:  "bar",
+				Usage: "件数を棒グラフで表示します",
+			},
+			cli.IntFlag{
+				Name:  "width",
+				Value: 50,
+				Usage: "棒グラフの最大の長さを指定します",
+			},
+			cli.BoolFlag{
+				Name:  "csv",
+				Usage: "CSV で出力します",
+			},
+		)...),
+		Action: commandAction(bucket),
+	}
+}
+
+// bucketSize 区切りの大きさ. n 単位ごとに区切ります.
+type bucketSize struct {
+	n    int
+	unit byte
+}
+
+func parseBucketSize(s string) (bucketSize, error) {
+	m := offsetRegexp.FindStringSubmatch(s)
+	if m == nil || m[2] == "B" {
+		return bucketSize{}, fmt.Errorf("'%s' is invalid size.", s)
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil || n < 1 {
+		return bucketSize{}, fmt.Errorf("'%s' is invalid size.", s)
+	}
+	return bucketSize{n: n, unit: m[2][0]}, nil
+}
+
+// start t を含む区切りの始まり. 区切りの始まりが夏時間の切り替えで存在しないときは --on-dst に従います.
+func (b bucketSize) start(t time.Time) (time.Time, error) {
+	return truncateBy(t, b.n, b.unit)
+}
+
+// next start の次の区切りの始まり
+func (b bucketSize) next(start time.Time) (time.Time, error) {
+	var t time.Time
+	switch b.unit {
+	case 'Y':
+		t = start.AddDate(b.n, 0, 0)
+	case 'M':
+		t = start.AddDate(0, b.n, 0)
+	case 'W':
+		t = start.AddDate(0, 0, 7*b.n)
+	case 'D':
+		t = start.AddDate(0, 0, b.n)
+	default:
+		t = start.Add(time.Duration(b.n) * clockUnits[b.unit])
+	}
+	// 月末や日付の変わり目で区切りがずれるので切り捨て直す
+	return b.start(t)
+}
+
+// bucketCount 区切りの始まりと件数
+type bucketCount struct {
+	start time.Time
+	count int
+}
+
+func bucket(c *cli.Context) error {
+	size, err := parseBucketSize(c.String("size"))
+	if err != nil {
+		return err
+	}
+	if c.Bool("bar") && c.Bool("csv") {
+		return errors.New("--bar and --csv can not be used together.")
+	}
+
+	extract := func(line string) (string, bool) {
+		s := strings.TrimSpace(line)
+		return s, s != ""
+	}
+	if c.String("field") != "" || c.String("pattern") != "" || c.String("i") != "" {
+		if extract, err = timestampExtractor(c); err != nil {
+			return err
+		}
+	}
+
+	buckets, err := countBuckets(clo.inStream, extract, size)
+	if err != nil {
+		return err
+	}
+
+	labels := make(map[int64]string, len(buckets))
+	for _, b := range buckets {
+		s, err := formatDt(&Dt{time: b.start, format: defaultFormat}, c.String("o"), zone)
+		if err != nil {
+			return err
+		}
+		labels[b.start.UnixNano()] = s
+	}
+	label := func(t time.Time) string {
+		return labels[t.UnixNano()]
+	}
+	switch {
+	case c.Bool("csv"):
+		return writeBucketsCSV(clo.outStream, buckets, label)
+	case c.Bool("bar"):
+		writeBucketsBar(clo.outStream, buckets, label, c.Int("width"))
+	default:
+		for _, b := range buckets {
+			fmt.Fprintf(clo.outStream, "%s %d\n", label(b.start), b.count)
+		}
+	}
+	return nil
+}
+
+// countBuckets in の日時を区切りごとに数える. 最初から最後までの区切りを, 件数のないものも含めて返します.
+func countBuckets(in io.Reader, extract func(string) (string, bool), size bucketSize) ([]bucketCount, error) {
+	counts := map[int64]int{}
+	var first, last time.Time
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		t, ok := lineTimestamp(extract, scanner.Text())
+		if ok == false {
+			continue
+		}
+		start, err := size.start(t.In(localLocation()))
+		if err != nil {
+			return nil, err
+		}
+		counts[start.Unix()]++
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if last.IsZero() || start.After(last) {
+			last = start
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(counts) == 0 {
+		return nil, nil
+	}
+
+	var buckets []bucketCount
+	for t := first; t.After(last) == false; {
+		buckets = append(buckets, bucketCount{start: t, count: counts[t.Unix()]})
+		next, err := size.next(t)
+		if err != nil {
+			return nil, err
+		}
+		t = next
+	}
+	return buckets, nil
+}
+
+func writeBucketsCSV(out io.Writer, buckets []bucketCount, label func(time.Time) string) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"bucket", "count"})
+	for _, b := range buckets {
+		w.Write([]string{label(b.start), strconv.Itoa(b.count)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// writeBucketsBar 件数の最大値を width としたときの長さの棒を表示する.
+func writeBucketsBar(out io.Writer, buckets []bucketCount, label func(time.Time) string, width int) {
+	max := 0
+	for _, b := range buckets {
+		if b.count > max {
+			max = b.count
+		}
+	}
+	digits := len(strconv.Itoa(max))
+	for _, b := range buckets {
+		n := 0
+		if max > 0 {
+			n = (b.count*width + max - 1) / max
+		}
+		line := fmt.Sprintf("%s %*d %s", label(b.start), digits, b.count, strings.Repeat("#", n))
+		fmt.Fprintln(out, strings.TrimRight(line, " "))
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBucketSize_next(t *testing.T) {
+	params := []struct {
+		size   string
+		start  string
+		expect string
+	}{
+		{size: "5m", start: "2024-01-31 23:55:00", expect: "2024-02-01 00:00:00"},
+		{size: "5h", start: "2024-01-31 20:00:00", expect: "2024-02-01 00:00:00"},
+		{size: "10D", start: "2024-01-31 00:00:00", expect: "2024-02-01 00:00:00"},
+		{size: "10D", start: "2024-01-21 00:00:00", expect: "2024-01-31 00:00:00"},
+		{size: "3M", start: "2024-10-01 00:00:00", expect: "2025-01-01 00:00:00"},
+	}
+
+	for _, p := range params {
+		size, err := parseBucketSize(p.size)
+		if err != nil {
+			t.Fatalf("parseBucketSize(%s) = %v", p.size, err)
+		}
+		start, _ := time.Parse("2006-01-02 15:04:05", p.start)
+		next, err := size.next(start)
+		actual := next.Format("2006-01-02 15:04:05")
+		if err != nil || actual != p.expect {
+			t.Errorf("next(%s, %s) = %s, %v; want %s", p.size, p.start, actual, err, p.expect)
+		}
+	}
+}
+
+func TestParseBucketSize_invalid(t *testing.T) {
+	for _, s := range []string{"", "0m", "1B", "1h30m", "m"} {
+		if _, err := parseBucketSize(s); err == nil {
+			t.Errorf("parseBucketSize(%s) = nil; want error", s)
+		}
+	}
+}
+
+func TestRun_bucket(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	input := strings.Join([]string{
+		"2024-01-31 10:01:00",
+		"2024-01-31 10:03:00",
+		"2024-01-31 10:04:59",
+		"2024-01-31 10:17:00",
+		"2024-01-31T01:06:00Z",
+		"not a date",
+	}, "\n")
+
+	params := []struct {
+		args   []string
+		input  string
+		expect []string
+	}{
+		{
+			args:   []string{"--tz", "Asia/Tokyo", "-s", "5m"},
+			input:  input,
+			expect: []string{"2024/01/31 10:00:00 3", "2024/01/31 10:05:00 1", "2024/01/31 10:10:00 0", "2024/01/31 10:15:00 1"},
+		},
+		{
+			args:   []string{"--tz", "Asia/Tokyo", "-s", "5m", "--bar", "--width", "6", "-o", "15:04"},
+			input:  input,
+			expect: []string{"10:00 3 ######", "10:05 1 ##", "10:10 0", "10:15 1 ##"},
+		},
+		{
+			args:   []string{"--tz", "Asia/Tokyo", "-s", "1D", "--csv", "-o", "YMD-"},
+			input:  input,
+			expect: []string{"bucket,count", "2024-01-31,5"},
+		},
+		{
+			args:   []string{"--tz", "UTC", "-f", "2", "-s", "1h", "-o", "RFC3339"},
+			input:  "GET 2024-01-31T10:59:59+09:00\nGET 2024-01-31T02:00:00Z\n",
+			expect: []string{"2024-01-31T01:00:00Z 1", "2024-01-31T02:00:00Z 1"},
+		},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{inStream: strings.NewReader(p.input), outStream: outStream, errStream: errStream}
+
+		status := clo.Run(append([]string{AppName, "bucket"}, p.args...))
+		if status != ExitCodeOK {
+			t.Fatalf("Run(%v): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream)
+		}
+		expect := strings.Join(p.expect, "\n") + "\n"
+		if outStream.String() != expect {
+			t.Errorf("Run(%v): Output = %q; want %q", p.args, outStream, expect)
+		}
+	}
+}
+
+func TestRun_bucketDSTError(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	// サンティアゴは 2024-09-08 00:00 が存在しないので 1 時間の区切りの始まりも決まらない
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	clo := &CLO{inStream: strings.NewReader("2024-09-08T05:10:00Z\n2024-09-08T06:20:00Z\n"), outStream: outStream, errStream: errStream}
+
+	args := []string{AppName, "bucket", "--tz", "America/Santiago", "--on-dst", "error", "-s", "1h"}
+	if status := clo.Run(args); status != ExitCodeError {
+		t.Errorf("Run(%v): ExitStatus = %d; want %d", args, status, ExitCodeError)
+	}
+	if outStream.Len() != 0 {
+		t.Errorf("Run(%v): Output = %q; want empty", args, outStream)
+	}
+	if expect := "does not exist in America/Santiago."; strings.Contains(errStream.String(), expect) == false {
+		t.Errorf("Run(%v): Error = %q; want %q", args, errStream, expect)
+	}
+}
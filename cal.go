@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/urfave/cli"
+)
+
+const (
+	calMonthsPerRow = 3
+	calWeeks        = 6
+
+	colorReverse   = "\x1b[7m"
+	colorUnderline = "\x1b[4m"
+	colorRed       = "\x1b[31m"
+	colorReset     = "\x1b[0m"
+)
+
+var (
+	weekdaysEn = []string{"Su", "Mo", "Tu", "We", "Th", "Fr", "Sa"}
+	weekdaysJa = []string{"日", "月", "火", "水", "木", "金", "土"}
+)
+
+func calCommand() cli.Command {
+	return cli.Command{
+		Name:      "cal",
+		Usage:     "計算した日付をカレンダーで表示します",
+		UsageText: AppName + " cal [options] [date [expr [expr ...]]]",
+		Description: `計算した日付と今日, 祝日を強調してカレンダーを表示します.
+   端末でないときや --color never のときは, 計算した日付に >, 今日に +, 祝日に * をつけます.`,
+		HideHelp: true,
+		Flags: commandFlags(
+			cli.IntFlag{
+				Name:  "months, n",
+				Value: 1,
+				Usage: "表示する月数を指定します",
+			},
+			cli.BoolFlag{
+				Name:  "year, y",
+				Usage: "1 年分を表示します",
+			},
+			cli.StringFlag{
+				Name:  "week-start",
+				Usage: "週の始まりの曜日を指定します (monday, sunday). デフォルトは設定ファイルの week-start か monday",
+			},
+			cli.BoolFlag{
+				Name:  "week-numbers, w",
+				Usage: "ISO 週番号を表示します",
+			},
+			cli.StringFlag{
+				Name:  "color",
+				Value: "auto",
+				Usage: "色をつけるかを指定します (auto, always, never)",
+			},
+		),
+		Action: commandAction(cal),
+	}
+}
+
+// calendar カレンダーの表示方法
+type calendar struct {
+	weekStart   time.Weekday
+	weekNumbers bool
+	lang        string
+	color       bool
+	// yearView 1 年分を表示するときは月のタイトルに年を含めない
+	yearView bool
+	target   time.Time
+	today    time.Time
+}
+
+func cal(c *cli.Context) error {
+	v, err := evalArgs(c, c.Args())
+	if err != nil {
+		return err
+	}
+	if v.dt == nil {
+		return evalError(errors.New("cal needs a date."))
+	}
+	target := v.dt.time
+	if zone != nil {
+		target = target.In(zone)
+	}
+
+	cl := &calendar{
+		weekNumbers: c.Bool("w"),
+		lang:        c.String("lang"),
+		target:      target,
+		today:       now(),
+	}
+	cl.weekStart = weekStart
+	if s := c.String("week-start"); s != "" {
+		if cl.weekStart, err = parseWeekStart(s); err != nil {
+			return err
+		}
+	}
+	switch c.String("color") {
+	case "always":
+		cl.color = true
+	case "never":
+	case "auto":
+		f, ok := clo.outStream.(*os.File)
+		cl.color = ok && isTerminal(f)
+	default:
+		return fmt.Errorf("'%s' is invalid color.", c.String("color"))
+	}
+
+	first := time.Date(target.Year(), target.Month(), 1, 0, 0, 0, 0, target.Location())
+	count := c.Int("n")
+	if c.Bool("y") {
+		cl.yearView = true
+		first = time.Date(target.Year(), time.January, 1, 0, 0, 0, 0, target.Location())
+		count = 12
+		title := fmt.Sprint(target.Year())
+		if cl.lang == "ja" {
+			title += "年"
+		}
+		fmt.Fprintln(clo.outStream, strings.TrimRight(center(title, calMonthsPerRow*cl.width()+2*(calMonthsPerRow-1)), " "))
+	}
+	if count < 1 {
+		return fmt.Errorf("'%d' is invalid months.", count)
+	}
+
+	var months []time.Time
+	for i := 0; i < count; i++ {
+		months = append(months, first.AddDate(0, i, 0))
+	}
+	cl.render(months)
+	return nil
+}
+
+// render 月を横に 3 つずつ並べて表示し, 最後に祝日の一覧を表示する.
+func (cl *calendar) render(months []time.Time) {
+	for i := 0; i < len(months); i += calMonthsPerRow {
+		end := i + calMonthsPerRow
+		if end > len(months) {
+			end = len(months)
+		}
+
+		var blocks [][]string
+		for _, m := range months[i:end] {
+			blocks = append(blocks, cl.month(m.Year(), m.Month()))
+		}
+		if i > 0 {
+			fmt.Fprintln(clo.outStream)
+		}
+		for row := range blocks[0] {
+			var cols []string
+			for _, b := range blocks {
+				cols = append(cols, b[row])
+			}
+			fmt.Fprintln(clo.outStream, strings.TrimRight(strings.Join(cols, "  "), " "))
+		}
+	}
+
+	var names []string
+	for _, m := range months {
+		for d := m; d.Month() == m.Month(); d = d.AddDate(0, 0, 1) {
+			if name, ok := holidayName(d); ok {
+				names = append(names, fmt.Sprintf("* %s %s", d.Format("2006-01-02"), name))
+			}
+		}
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		fmt.Fprintln(clo.outStream)
+		for _, n := range names {
+			fmt.Fprintln(clo.outStream, n)
+		}
+	}
+}
+
+func (cl *calendar) width() int {
+	if cl.weekNumbers {
+		return 8 * 3
+	}
+	return 7 * 3
+}
+
+// month 1 ヶ月分の行. 各行は同じ表示幅です.
+func (cl *calendar) month(year int, month time.Month) []string {
+	var title string
+	switch {
+	case cl.lang == "ja" && cl.yearView:
+		title = fmt.Sprintf("%d月", month)
+	case cl.lang == "ja":
+		title = fmt.Sprintf("%d年%d月", year, month)
+	case cl.yearView:
+		title = month.String()
+	default:
+		title = fmt.Sprintf("%s %d", month, year)
+	}
+	lines := []string{center(title, cl.width())}
+
+	names := weekdaysEn
+	if cl.lang == "ja" {
+		names = weekdaysJa
+	}
+	var header strings.Builder
+	if cl.weekNumbers {
+		header.WriteString("   ")
+	}
+	for i := 0; i < 7; i++ {
+		name := names[(int(cl.weekStart)+i)%7]
+		header.WriteString(strings.Repeat(" ", 3-displayWidth(name)) + name)
+	}
+	lines = append(lines, header.String())
+
+	loc := cl.target.Location()
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	day := first.AddDate(0, 0, -((int(first.Weekday()) - int(cl.weekStart) + 7) % 7))
+	for w := 0; w < calWeeks; w++ {
+		var line strings.Builder
+		if cl.weekNumbers {
+			// 行に含まれる月曜日の ISO 週番号
+			monday := day.AddDate(0, 0, (8-int(day.Weekday()))%7)
+			_, week := monday.ISOWeek()
+			fmt.Fprintf(&line, "%2d ", week)
+		}
+		for i := 0; i < 7; i++ {
+			if day.Month() == month {
+				line.WriteString(cl.cell(day))
+			} else {
+				line.WriteString("   ")
+			}
+			day = day.AddDate(0, 0, 1)
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+func (cl *calendar) cell(day time.Time) string {
+	_, holiday := holidayName(day)
+	isTarget := sameDate(day, cl.target)
+	isToday := sameDate(day, cl.today)
+
+	if cl.color == false {
+		marker := " "
+		switch {
+		case isTarget:
+			marker = ">"
+		case isToday:
+			marker = "+"
+		case holiday:
+			marker = "*"
+		}
+		// 印は数字の直前に置く
+		d := fmt.Sprint(day.Day())
+		return strings.Repeat(" ", 2-len(d)) + marker + d
+	}
+
+	var style string
+	if isTarget {
+		style += colorReverse
+	}
+	if isToday {
+		style += colorUnderline
+	}
+	if holiday {
+		style += colorRed
+	}
+	if style == "" {
+		return fmt.Sprintf(" %2d", day.Day())
+	}
+	return fmt.Sprintf(" %s%2d%s", style, day.Day(), colorReset)
+}
+
+func sameDate(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// displayWidth 端末での表示幅. ASCII 以外の文字は 2 文字分として数えます.
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			w++
+		} else {
+			w += 2
+		}
+	}
+	return w
+}
+
+func center(s string, width int) string {
+	pad := width - displayWidth(s)
+	if pad <= 0 {
+		return s
+	}
+	return strings.Repeat(" ", pad/2) + s + strings.Repeat(" ", pad-pad/2)
+}
+
+// parseWeekStart 週の始まりの曜日 (monday, sunday) を解釈する.
+func parseWeekStart(s string) (time.Weekday, error) {
+	switch s {
+	case "monday":
+		return time.Monday, nil
+	case "sunday":
+		return time.Sunday, nil
+	default:
+		return time.Monday, fmt.Errorf("'%s' is invalid week start.", s)
+	}
+}
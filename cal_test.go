@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_cal(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	nowInterface = &MyTime{}
+	path := filepath.Join(dir, "holidays")
+	if err := ioutil.WriteFile(path, []byte("2018-05-03 Constitution Day\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	params := []struct {
+		args   []string
+		expect []string
+	}{
+		{
+			args: []string{"2018-05-12", "+3D"},
+			expect: []string{
+				"      May 2018",
+				" Mo Tu We Th Fr Sa Su",
+				"     1  2 *3  4  5  6",
+				"  7  8  9 10 11+12 13",
+				" 14>15 16 17 18 19 20",
+				" 21 22 23 24 25 26 27",
+				" 28 29 30 31",
+				"",
+				"",
+				"* 2018-05-03 Constitution Day",
+			},
+		},
+		{
+			args: []string{"--week-start", "sunday", "-w", "--lang", "ja", "2018-05-01"},
+			expect: []string{
+				"       2018年5月",
+				"    日 月 火 水 木 金 土",
+				"18        >1  2 *3  4  5",
+				"19   6  7  8  9 10 11+12",
+				"20  13 14 15 16 17 18 19",
+				"21  20 21 22 23 24 25 26",
+				"22  27 28 29 30 31",
+				"23",
+				"",
+				"* 2018-05-03 Constitution Day",
+			},
+		},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+		args := append([]string{AppName, "cal", "--color", "never", "--holidays", path}, p.args...)
+
+		status := clo.Run(args)
+		if status != ExitCodeOK {
+			t.Fatalf("Run(%v): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream)
+		}
+		expect := strings.Join(p.expect, "\n") + "\n"
+		if outStream.String() != expect {
+			t.Errorf("Run(%v): Output =\n%s\nwant\n%s", p.args, outStream, expect)
+		}
+	}
+}
+
+func TestRun_calYear(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	nowInterface = &MyTime{}
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	clo := &CLO{outStream: outStream, errStream: errStream}
+
+	status := clo.Run([]string{AppName, "cal", "--color", "never", "-y", "2018-05-12"})
+	if status != ExitCodeOK {
+		t.Fatalf("Run(cal -y): ExitStatus = %d; want %d: %s", status, ExitCodeOK, errStream)
+	}
+	lines := strings.Split(outStream.String(), "\n")
+	if strings.TrimSpace(lines[0]) != "2018" {
+		t.Errorf("Run(cal -y): title = %q; want 2018", lines[0])
+	}
+	if strings.Fields(lines[1])[0] != "January" || strings.Contains(outStream.String(), "December") == false {
+		t.Errorf("Run(cal -y): Output = %s; want January to December", outStream)
+	}
+}
+
+func TestRun_calError(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	params := [][]string{
+		{"--week-start", "friday", "2018-05-12"},
+		{"--color", "sometimes", "2018-05-12"},
+		{"-n", "0", "2018-05-12"},
+		{"1D"},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+
+		status := clo.Run(append([]string{AppName, "cal"}, p...))
+		if status != ExitCodeError {
+			t.Errorf("Run(%v): ExitStatus = %d; want %d", p, status, ExitCodeError)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 
 	"log"
@@ -30,6 +31,7 @@ const (
 
 // CLO コマンドのメインの構造体
 type CLO struct {
+	inStream             io.Reader
 	outStream, errStream io.Writer
 }
 
@@ -72,6 +74,23 @@ func (c *CLO) Run(args []string) int {
 	cli.AppHelpTemplate = appHelpTemplate()
 	cli.HelpPrinter = helpPrinter(cli.HelpPrinter)
 
+	app.Commands = []cli.Command{
+		replCommand(),
+		serveCommand(),
+		calCommand(),
+		watchCommand(),
+		meetCommand(),
+		cronCommand(),
+		rruleCommand(),
+		rewriteCommand(),
+		filterCommand(),
+		sortCommand(),
+		bucketCommand(),
+		csvCommand(),
+		jsonCommand(),
+		icsCommand(),
+		configCommand(),
+	}
 	app.Action = action()
 	app.Writer = c.outStream
 	app.ErrWriter = c.errStream
@@ -103,6 +122,7 @@ func description() string {
   日付のフォーマットは, 入力から自動で判断されます. 利用できるフォー
   マットについては DATE FORMATS を参照してください. 計算元の日付が
   数字のみで構成される場合は, 自動的に unix 秒と判断されます.
+  @1526113800 のように @ をつけることもできます.
 
   $ dt -o def 1526113800 +1Y +3M +20s
   2019/08/12 17:30:20
@@ -123,6 +143,22 @@ func description() string {
 
   $ dt -o ANSIC 1526113800 +1Y +3M +20s
   Mon Aug 12 17:30:20 2019
+
+  計算式をひとつの引数で指定することもできます. @startM や @endD で月や日の
+  始まりと終わりに丸め, B で営業日を計算します.
+
+  $ dt '2024-01-31 + 1M - 1D'
+  2024-03-01
+
+  $ dt 'let d = 2024-03-01; d @startM - 1B'
+  2024-02-29
+
+  出力フォーマットに ulid, uuidv7, objectid, ksuid, snowflake を指定すると,
+  その日時における最小の ID を出力します. 末尾に -rand をつけると乱数部分を
+  ランダムにします.
+
+  $ dt -o ulid 1526113800 -1D
+  01CD754CT00000000000000000
 `
 }
 
@@ -132,6 +168,37 @@ func flags() []cli.Flag {
 			Name:  "adjust-day, a",
 			Usage: "結果日付が無効なときその月末日に調整します",
 		},
+		cli.StringFlag{
+			Name:  "on-invalid-day",
+			Usage: "結果日付が無効なときの扱いを指定します (normalize, clamp, error). -a より優先します",
+		},
+		cli.StringFlag{
+			Name:  "on-dst",
+			Value: "compatible",
+			Usage: "夏時間の切り替えで存在しない時刻や 2 回ある時刻の扱いを指定します (compatible, earlier, later, error)",
+		},
+		cli.BoolFlag{
+			Name:  "warn-dst",
+			Usage: "計算が夏時間の切り替えをまたいだときや切り替えの時刻になったときに警告を出力します",
+		},
+		cli.StringFlag{
+			Name:  "scale",
+			Value: "utc",
+			Usage: "入力の日時の時刻系を指定します (utc, tai, gps)",
+		},
+		cli.StringFlag{
+			Name:  "output-scale",
+			Value: "utc",
+			Usage: "出力する日時の時刻系を指定します (utc, tai, gps)",
+		},
+		cli.BoolFlag{
+			Name:  "show-leap-second",
+			Usage: "UTC のうるう秒を 23:59:60 のように表示します",
+		},
+		cli.StringFlag{
+			Name:  "leap-seconds",
+			Usage: "うるう秒の表のファイルを指定します (デフォルトは ~/.config/dt/leap-seconds.list. なければ組み込みの表)",
+		},
 		cli.BoolFlag{
 			Name:  "debug, d",
 			Usage: "デバッグログを出力します",
@@ -148,6 +215,75 @@ func flags() []cli.Flag {
 			Name:  "output-format, o",
 			Usage: "出力フォーマットを指定します",
 		},
+		cli.StringFlag{
+			Name:  "now",
+			Usage: "現在時刻を指定した日時に固定します (デフォルトは環境変数 SOURCE_DATE_EPOCH の unix 秒か現在時刻)",
+		},
+		cli.StringFlag{
+			Name:  "summary",
+			Usage: "-o ics の予定の件名を指定します",
+		},
+		cli.StringFlag{
+			Name:  "uid",
+			Usage: "-o ics の予定の UID を計算式の代わりに作る文字列を指定します",
+		},
+		cli.StringFlag{
+			Name:  "event-duration",
+			Usage: "-o ics の予定の長さを指定します (例: 1h, PT30M. --all-day のときは P2D のように日数)",
+		},
+		cli.BoolFlag{
+			Name:  "all-day",
+			Usage: "-o ics の予定を終日の予定にします",
+		},
+		cli.StringFlag{
+			Name:  "relative-to",
+			Usage: "-o relative や -o diff の基準日時を指定します (デフォルトは現在時刻)",
+		},
+		cli.IntFlag{
+			Name:  "granularity",
+			Value: 1,
+			Usage: "-o relative で表示する単位の数を指定します",
+		},
+		cli.StringFlag{
+			Name:  "rounding",
+			Value: "floor",
+			Usage: "-o relative の端数の扱いを指定します (floor, round, ceil)",
+		},
+		cli.StringFlag{
+			Name:  "lang",
+			Value: "en",
+			Usage: "-o relative の表示言語を指定します (en, ja)",
+		},
+		cli.StringFlag{
+			Name:  "config",
+			Usage: "設定ファイルを指定します. 指定したときは他の設定ファイルを読みません",
+		},
+		cli.BoolFlag{
+			Name:  "no-config",
+			Usage: "設定ファイルを読みません. 環境変数の設定は使います",
+		},
+		cli.StringFlag{
+			Name:  "holidays",
+			Usage: "祝日のファイルを指定します (デフォルトは ~/.config/dt/holidays)",
+		},
+		cli.StringFlag{
+			Name:  "tz, z",
+			Usage: "タイムゾーンを指定します (例: Asia/Tokyo, UTC)",
+		},
+		cli.StringFlag{
+			Name:  "zones",
+			Usage: "日時をカンマ区切りのタイムゾーンごとに表示します. 設定ファイルの [zones] のグループの名前も指定できます",
+		},
+		cli.StringFlag{
+			Name:  "working-hours",
+			Value: defaultWorkingHours,
+			Usage: "--zones で就業時間外を示す就業時間を指定します. タイムゾーンごとには Europe/Berlin@08:00-17:00 のように指定します",
+		},
+		cli.StringFlag{
+			Name:  "weekend",
+			Value: defaultWeekend,
+			Usage: "--zones で就業時間外にする週末の曜日を fri,sat や fri-sat のように指定します. タイムゾーンごとには Asia/Riyadh@09:00-17:00@fri-sat のように指定します",
+		},
 		cli.BoolFlag{
 			Name:  "version, v",
 			Usage: "バージョンを表示します",
@@ -167,7 +303,10 @@ DESCRIPTION:
 	
 OPTIONS:
   {{range .Flags}}{{.}}
-  {{end}}{{if .DateFormats}}
+  {{end}}{{if .VisibleCommands}}
+COMMANDS:
+  {{range .VisibleCommands}}{{.Name}}	{{.Usage}}
+  {{end}}{{end}}{{if .DateFormats}}
 DATE FORMATS:
   {{range .DateFormats}}{{.}}
   {{end}}{{end}}
@@ -176,7 +315,11 @@ DATE FORMATS:
 
 func helpPrinter(printer func(w io.Writer, templ string, d interface{})) func(w io.Writer, templ string, d interface{}) {
 	return func(w io.Writer, templ string, d interface{}) {
-		app := d.(*cli.App)
+		app, ok := d.(*cli.App)
+		if ok == false {
+			printer(w, templ, d)
+			return
+		}
 		data := newHelpData(app)
 		printer(w, templ, data)
 	}
@@ -206,7 +349,8 @@ func action() func(c *cli.Context) error {
 		cliContext = c
 
 		if c.Bool("h") == true {
-			loadConfig()
+			// ヘルプには設定ファイルのフォーマットも表示する
+			loadConfig(c.String("config"), c.Bool("no-config"))
 			cli.ShowAppHelp(c)
 			return nil
 		}
@@ -214,49 +358,175 @@ func action() func(c *cli.Context) error {
 			cli.ShowVersion(c)
 			return nil
 		}
-		if c.Bool("d") == false {
-			log.SetOutput(ioutil.Discard)
+		if err := setup(c); err != nil {
+			return err
+		}
+
+		v, err := evalArgs(c, c.Args())
+		if err != nil {
+			return err
+		}
+
+		if len(worldZones) > 0 {
+			return outputZones(v.dt)
+		}
+		if v.dt == nil {
+			return outputDuration(v)
+		}
+		return output(v.dt)
+	}
+}
+
+// evalArgs 引数を順に評価する. 引数がないときは現在時刻です.
+func evalArgs(c *cli.Context, args []string) (value, error) {
+	e := newEvaluator(c.String("i"), adjustPolicy(c))
+	e.current = value{dt: &Dt{time: now(), format: defaultFormat}}
+	for i, arg := range args {
+		if err := processArg(e, i, arg); err != nil {
+			return e.current, err
+		}
+	}
+	return e.current, nil
+}
+
+// setup コマンドの実行前の共通の準備. サブコマンドからも使います.
+func setup(c *cli.Context) error {
+	cliContext = c
+
+	if c.Bool("d") == false {
+		log.SetOutput(ioutil.Discard)
+	}
+
+	log.Printf("args: %s", c.Args())
+
+	if err := loadConfig(c.String("config"), c.Bool("no-config")); err != nil {
+		return err
+	}
+	if err := applyConfigDefaults(c); err != nil {
+		return err
+	}
+	if s := c.String("on-invalid-day"); s != "" {
+		if _, err := parseAdjustDay(s); err != nil {
+			return err
 		}
+	}
+	policy, err := parseDSTPolicy(c.String("on-dst"))
+	if err != nil {
+		return err
+	}
+	dstPolicy, warnDST = policy, c.Bool("warn-dst")
+	if inputScale, err = parseTimeScale(c.String("scale")); err != nil {
+		return err
+	}
+	if outputScale, err = parseTimeScale(c.String("output-scale")); err != nil {
+		return err
+	}
+	showLeapSecond = c.Bool("show-leap-second")
+	if err := loadLeapSeconds(c.String("leap-seconds")); err != nil {
+		return err
+	}
+	if err := loadHolidays(holidaySources(c)...); err != nil {
+		return err
+	}
+	if err := loadZone(c.String("tz")); err != nil {
+		return err
+	}
+	if err := loadZones(c); err != nil {
+		return err
+	}
+	return loadNow(c)
+}
 
-		log.Printf("args: %s", c.Args())
+// commandFlags サブコマンドで使うオプション. --version 以外のオプションと extra を受け付けます.
+func commandFlags(extra ...cli.Flag) []cli.Flag {
+	var result []cli.Flag
+	for _, f := range flags() {
+		if f.GetName() != "version, v" {
+			result = append(result, f)
+		}
+	}
+	return append(result, extra...)
+}
 
-		loadConfig()
+// commandAction サブコマンドの共通処理をしてから f を呼び出す.
+func commandAction(f func(c *cli.Context) error) func(c *cli.Context) error {
+	return func(c *cli.Context) error {
+		if c.Bool("h") == true {
+			return cli.ShowCommandHelp(c, c.Command.Name)
+		}
+		if err := inheritGlobalFlags(c); err != nil {
+			return err
+		}
+		if err := setup(c); err != nil {
+			return err
+		}
+		return f(c)
+	}
+}
 
-		var dt = &Dt{time: now(), format: defaultFormat}
-		for i, arg := range c.Args() {
-			newDt, err := processArg(i, arg, dt)
-			if err != nil {
+// inheritGlobalFlags サブコマンドの前に指定したオプションをサブコマンドのオプションにする.
+// 両方で指定したときはサブコマンドの後のオプションを優先します.
+func inheritGlobalFlags(c *cli.Context) error {
+	for _, f := range flags() {
+		names := strings.Split(f.GetName(), ",")
+		name := strings.TrimSpace(names[0])
+		if name == "version" || c.IsSet(name) || c.GlobalIsSet(name) == false {
+			continue
+		}
+		v := c.GlobalString(name)
+		for _, n := range names {
+			if err := c.Set(strings.TrimSpace(n), v); err != nil {
 				return err
 			}
-			dt = newDt
 		}
-
-		output(dt)
-		return nil
 	}
+	return nil
 }
 
-func processArg(i int, arg string, dt *Dt) (*Dt, error) {
-	log.Printf("arg[%d]: %s, time: %v", i, arg, dt.time)
+func processArg(e *evaluator, i int, arg string) error {
+	log.Printf("arg[%d]: %s, value: %+v", i, arg, e.current)
 
 	if i == 0 {
-		return processFirst(arg)
+		return e.first(arg)
 	}
-	return processRest(arg, dt)
+	return e.rest(arg)
 }
 
 func processFirst(arg string) (*Dt, error) {
+	return parseDate(arg, cliContext.String("i"), nil)
+}
+
+// parseDate 入力フォーマット inputFormat を優先して日時を解釈する.
+// loc はタイムゾーンのない日時のタイムゾーンです. nil のときは localLocation() を使います.
+func parseDate(arg, inputFormat string, loc *time.Location) (*Dt, error) {
+	if loc == nil {
+		loc = localLocation()
+	}
+	// dstErr 解析できたが夏時間の切り替えで時刻が決まらなかったときのエラー
+	var dstErr error
 	parse := func(f, v string) (time.Time, error) {
 		if strings.Contains(f, "MST") {
 			return time.Parse(f, arg)
 		}
-		return time.ParseInLocation(f, arg, localLocation())
+		if strings.Contains(f, "Z07") || strings.Contains(f, "-07") {
+			return time.ParseInLocation(f, arg, loc)
+		}
+		// 時差のない日時は夏時間の切り替えを dstPolicy に従って決める
+		wall, err := time.Parse(f, arg)
+		if err != nil {
+			return wall, err
+		}
+		t, err := localDate(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), loc)
+		if err != nil {
+			dstErr = err
+		}
+		return t, err
 	}
 
 	functions := []func(s string) *Dt{
 		func(s string) *Dt {
 			// 入力フォーマット指定
-			f := cliContext.String("i")
+			f := inputFormat
 			if v, ok := formats[f]; ok {
 				f = v
 			}
@@ -264,6 +534,13 @@ func processFirst(arg string) (*Dt, error) {
 			switch f {
 			case def:
 				return nil
+			case unixSeconds:
+				match, _ := regexp.MatchString(`^\d+$`, arg)
+				if match == false {
+					return nil
+				}
+				unixSec, _ := strconv.ParseInt(arg, 10, 64)
+				return &Dt{time: time.Unix(unixSec, 0), format: unixSeconds}
 			case unixMilliSeconds:
 				match, _ := regexp.MatchString(`^\d+$`, arg)
 				if match == false {
@@ -271,6 +548,14 @@ func processFirst(arg string) (*Dt, error) {
 				}
 				milliSec, _ := strconv.Atoi(arg)
 				return &Dt{time: time.Unix(0, int64(milliSec)*int64(time.Millisecond)), format: unixMilliSeconds}
+			case gpsSeconds:
+				match, _ := regexp.MatchString(`^\d+$`, arg)
+				if match == false {
+					return nil
+				}
+				sec, _ := strconv.ParseInt(arg, 10, 64)
+				t, leap := fromScale(gpsEpoch.Add(time.Duration(sec)*time.Second), ScaleGPS)
+				return &Dt{time: t.In(loc), format: gpsSeconds, leap: leap}
 			default:
 				t, err := parse(f, arg)
 				if err == nil {
@@ -282,22 +567,25 @@ func processFirst(arg string) (*Dt, error) {
 		func(s string) *Dt {
 			// 現在時刻
 			if arg == "now" {
-				return &Dt{time: now(), format: defaultFormat}
+				return &Dt{time: now().In(loc), format: defaultFormat}
 			}
 			return nil
 		},
 		func(s string) *Dt {
-			// unix 秒として解釈
-			match, _ := regexp.MatchString(`^\d+$`, arg)
-			if match == true {
-				unixSec, _ := strconv.Atoi(arg)
-				return &Dt{time: time.Unix(int64(unixSec), 0), format: unixSeconds}
+			// 数字だけの日時は unix 秒として解釈. @1526113800 のように @ をつけることもできます
+			if m := epochRegexp.FindStringSubmatch(arg); m != nil {
+				unixSec, err := strconv.ParseInt(m[1], 10, 64)
+				if err != nil {
+					return nil
+				}
+				return &Dt{time: time.Unix(unixSec, 0), format: unixSeconds}
 			}
 			return nil
 		},
 		func(s string) *Dt {
 			// 所定のフォーマットとして解釈
-			for _, f := range formats {
+			for _, name := range formatNames() {
+				f := formats[name]
 				t, err := parse(f, arg)
 				if err == nil {
 					return &Dt{time: t, format: f}
@@ -309,46 +597,51 @@ func processFirst(arg string) (*Dt, error) {
 
 	for _, f := range functions {
 		dt := f(arg)
-		if dt != nil {
-			return dt, nil
+		if dt == nil {
+			continue
 		}
+		// 現在時刻は UTC, GPS 秒は GPS 時刻なので --scale によらない
+		if inputScale != ScaleUTC && arg != "now" && dt.format != gpsSeconds {
+			dt.time, dt.leap = fromScale(dt.time, inputScale)
+		}
+		return dt, nil
 	}
 
+	if dstErr != nil {
+		return nil, dstErr
+	}
 	text := fmt.Sprintf("'%s' is invalid format.", arg)
 	return nil, errors.New(text)
 }
 
-func processRest(arg string, dt *Dt) (*Dt, error) {
-	match, _ := regexp.MatchString(`^[-+]?\d+[YMDhms]$`, arg)
-	if match == false {
-		text := fmt.Sprintf("'%s' is invalid format.", arg)
-		return dt, errors.New(text)
-	}
-
-	current := dt
-	month := getMonthFunc(cliContext.Bool("a"))
-	functions := []func(*Dt, string) (*Dt, error){year, month, day, hour, minute, second}
-	for i, f := range functions {
-		newDt, err := f(current, arg)
-		if err != nil || newDt.time != current.time {
-			log.Printf("func: %d, time: %v -> %v", i, current.time, newDt.time)
-			return newDt, err
-		}
-		current = newDt
+func adjustDay(adjust bool) AdjustDay {
+	if adjust {
+		return AdjustToEndOfMonth
 	}
-
-	return current, nil
+	return Normalize
 }
 
-func getMonthFunc(adjust bool) func(*Dt, string) (*Dt, error) {
-	adjustDay := Normalize
-	if adjust {
-		adjustDay = AdjustToEndOfMonth
+// parseAdjustDay --on-invalid-day の値を AdjustDay にする.
+func parseAdjustDay(s string) (AdjustDay, error) {
+	switch s {
+	case "normalize":
+		return Normalize, nil
+	case "clamp":
+		return AdjustToEndOfMonth, nil
+	case "error":
+		return ErrorOnInvalidDay, nil
+	default:
+		return Normalize, fmt.Errorf("'%s' is invalid day policy.", s)
 	}
+}
 
-	return func(dt *Dt, s string) (*Dt, error) {
-		return month(dt, s, adjustDay)
+// adjustPolicy --on-invalid-day と -a から AdjustDay を決める. 値は setup で検査済みです.
+func adjustPolicy(c *cli.Context) AdjustDay {
+	if s := c.String("on-invalid-day"); s != "" {
+		adjust, _ := parseAdjustDay(s)
+		return adjust
 	}
+	return adjustDay(c.Bool("a"))
 }
 
 // NowInterface テスト用のインタフェース
@@ -359,82 +652,248 @@ type NowInterface interface {
 
 var nowInterface NowInterface
 
+// pinnedNow --now か SOURCE_DATE_EPOCH で指定した現在時刻. 指定されていないときは nil
+var pinnedNow *time.Time
+
 func now() time.Time {
+	if pinnedNow != nil {
+		return pinnedNow.In(localLocation())
+	}
 	if nowInterface == nil {
-		t := time.Now()
-		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+		return time.Now().In(localLocation()).Truncate(time.Second)
+	}
+	if zone != nil {
+		return nowInterface.Now().In(zone)
 	}
 	return nowInterface.Now()
 }
 
 func localLocation() *time.Location {
+	if zone != nil {
+		return zone
+	}
 	if nowInterface == nil {
 		return time.Local
 	}
 	return nowInterface.Local()
 }
 
-func year(dt *Dt, s string) (*Dt, error) {
-	return addIfMatch(s, "^[-+]?\\d+Y$", func(i int) *Dt {
-		return dt.AddYear(i)
-	})
+// loadNow --now か環境変数 SOURCE_DATE_EPOCH で現在時刻を固定する. --now を優先します.
+func loadNow(c *cli.Context) error {
+	pinnedNow = nil
+	var t time.Time
+	if s := c.String("now"); s != "" {
+		dt, err := parseDate(s, c.String("i"), nil)
+		if err != nil {
+			return err
+		}
+		t = dt.time
+		log.Printf("now: %v (--now)", t)
+	} else if s, ok := os.LookupEnv("SOURCE_DATE_EPOCH"); ok && s != "" {
+		sec, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("SOURCE_DATE_EPOCH: '%s' is invalid unix time.", s)
+		}
+		t = time.Unix(sec, 0)
+		log.Printf("now: %v (SOURCE_DATE_EPOCH)", t)
+	} else {
+		return nil
+	}
+	pinnedNow = &t
+	return nil
 }
 
-func addIfMatch(s, pattern string, f func(i int) *Dt) (*Dt, error) {
-	match, _ := regexp.MatchString(pattern, s)
-	if match == false {
-		return f(0), nil
+// zone --tz で指定されたタイムゾーン. 指定されていないときは nil
+var zone *time.Location
+
+func loadZone(name string) error {
+	zone = nil
+	if name == "" {
+		return nil
 	}
 
-	duration, err := strconv.Atoi(s[0 : len(s)-1])
+	loc, err := loadLocation(name)
 	if err != nil {
-		return f(0), err
+		return err
 	}
-	return f(duration), nil
+	zone = loc
+	return nil
 }
 
-func month(dt *Dt, s string, adjustDay AdjustDay) (*Dt, error) {
-	return addIfMatch(s, "^[-+]?\\d+M$", func(i int) *Dt {
-		return dt.AddMonth(i, adjustDay)
-	})
+func loadLocation(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is invalid time zone.", name)
+	}
+	return loc, nil
 }
 
-func day(dt *Dt, s string) (*Dt, error) {
-	return addIfMatch(s, "^[-+]?\\d+D$", func(i int) *Dt {
-		return dt.AddDay(i)
-	})
+func output(dt *Dt) error {
+	return outputAs(dt, cliContext.String("o"))
 }
 
-func hour(dt *Dt, s string) (*Dt, error) {
-	return addIfMatch(s, "^[-+]?\\d+h$", func(i int) *Dt {
-		return dt.AddHour(i)
-	})
+// outputAll cron 式や RRULE の rule の繰り返しの日時を順に出力する. -o ics のときはすべての日時をひとつのカレンダーにし,
+// UID は rule とそれぞれの日時から作ります.
+func outputAll(rule string, dts []*Dt) error {
+	if cliContext.String("o") == icsFormat {
+		events := make([]icsEvent, len(dts))
+		for i, dt := range dts {
+			events[i] = icsEvent{dt: dt, summary: cliContext.String("summary"), source: rule, occurrence: dt.time}
+		}
+		return outputCalendar(events)
+	}
+	for _, dt := range dts {
+		if err := output(dt); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func minute(dt *Dt, s string) (*Dt, error) {
-	return addIfMatch(s, "^[-+]?\\d+m$", func(i int) *Dt {
-		return dt.AddMinute(i)
-	})
+func outputAs(dt *Dt, outputFormat string) error {
+	if outputFormat == icsFormat {
+		return outputCalendar([]icsEvent{{dt: dt, summary: cliContext.String("summary"), source: strings.Join(cliContext.Args(), " ")}})
+	}
+	s, err := formatOutput(dt, outputFormat)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(clo.outStream, "%s\n", s)
+	return nil
 }
 
-func second(dt *Dt, s string) (*Dt, error) {
-	return addIfMatch(s, "^[-+]?\\d+s$", func(i int) *Dt {
-		return dt.AddSecond(i)
-	})
+// formatOutput 出力フォーマットで日時を文字列にする. relative, diff, diff-iso も扱います.
+func formatOutput(dt *Dt, outputFormat string) (string, error) {
+	var s string
+	var err error
+	switch outputFormat {
+	case relativeFormat:
+		s, err = relative(dt)
+	case diffFormat, diffISOFormat:
+		var ref time.Time
+		ref, err = referenceTime()
+		if err != nil {
+			break
+		}
+		if outputFormat == diffISOFormat {
+			s = durationBetween(ref, dt.time).ISOString()
+		} else {
+			s = dt.time.Sub(ref).String()
+		}
+	case icsFormat:
+		err = evalError(errors.New("-o ics cannot be used here."))
+	default:
+		s, err = formatDt(dt, outputFormat, zone)
+	}
+	return s, err
 }
 
-func output(dt *Dt) {
-	outputFormat := cliContext.String("o")
+// transformDate s を最初の引数と同じ方法で解釈し, exprs を続けて評価して -o のフォーマットで出力する.
+func transformDate(s string, exprs []string) (string, error) {
+	e := newEvaluator(cliContext.String("i"), adjustPolicy(cliContext))
+	dt, err := e.parseDate(s)
+	if err != nil {
+		return "", err
+	}
+	e.current = value{dt: dt}
+	for _, expr := range exprs {
+		if err := e.rest(expr); err != nil {
+			return "", err
+		}
+	}
+	if e.current.dt == nil {
+		return "", evalError(fmt.Errorf("'%s' is not a date.", s))
+	}
+	return formatOutput(e.current.dt, cliContext.String("o"))
+}
+
+// formatDt 出力フォーマットで日時を文字列にする. --output-scale の時刻系に変換し,
+// loc が nil でないときはそのタイムゾーンに変換します.
+// ID フォーマットのパラメーターが正しくないときや, ID で表せない日時のときはエラーです.
+func formatDt(dt *Dt, outputFormat string, loc *time.Location) (string, error) {
+	result := *dt
 	switch outputFormat {
 	case "":
-		fmt.Fprintf(clo.outStream, "%v\n", dt)
 	case "def":
-		fmt.Fprintf(clo.outStream, "%s\n", &Dt{time: dt.time, format: defaultFormat})
+		result.format = defaultFormat
 	default:
+		result.format = outputFormat
 		if v, ok := formats[outputFormat]; ok {
-			fmt.Fprintf(clo.outStream, "%s\n", &Dt{time: dt.time, format: v})
-		} else {
-			fmt.Fprintf(clo.outStream, "%s\n", &Dt{time: dt.time, format: outputFormat})
+			result.format = v
 		}
 	}
+
+	// gps は常に GPS 時刻なので --output-scale によらない
+	if outputScale != ScaleUTC && result.format != gpsSeconds {
+		result.time, result.leap = toScale(result.time, result.leap, outputScale), false
+	}
+	if loc != nil {
+		result.time = result.time.In(loc)
+	}
+	if _, ok, err := formatID(result.time, result.format); ok && err != nil {
+		return "", evalError(err)
+	}
+	return result.String(), nil
+}
+
+func relative(dt *Dt) (string, error) {
+	ref, err := referenceTime()
+	if err != nil {
+		return "", err
+	}
+
+	rounding, err := parseRounding(cliContext.String("rounding"))
+	if err != nil {
+		return "", err
+	}
+	opt := RelativeOption{
+		Granularity: cliContext.Int("granularity"),
+		Rounding:    rounding,
+		Lang:        cliContext.String("lang"),
+	}
+	return Humanize(dt.time, ref, opt)
+}
+
+// outputDuration 計算結果が期間のときの出力. -o diff のときは Go 形式, それ以外は ISO 8601 形式.
+func outputDuration(v value) error {
+	return outputDurationAs(v, cliContext.String("o"))
+}
+
+func outputDurationAs(v value, outputFormat string) error {
+	s, err := formatDuration(v, outputFormat)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(clo.outStream, "%s\n", s)
+	return nil
+}
+
+func formatDuration(v value, outputFormat string) (string, error) {
+	if outputFormat == icsFormat {
+		return "", evalError(errors.New("-o ics needs a date."))
+	}
+	d, ok := v.duration()
+	if !ok {
+		return "", evalError(errors.New("business days cannot be printed as a duration."))
+	}
+
+	// Go 形式には日の単位がないので 1 日を 24 時間として扱う
+	if outputFormat == diffFormat && d.Years == 0 && d.Months == 0 {
+		return (time.Duration(d.Days)*24*time.Hour + d.Clock).String(), nil
+	}
+	return d.ISOString(), nil
+}
+
+// referenceTime -o relative や -o diff の基準日時. --relative-to がないときは現在時刻.
+func referenceTime() (time.Time, error) {
+	s := cliContext.String("relative-to")
+	if s == "" {
+		return now(), nil
+	}
+	dt, err := processFirst(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return dt.time, nil
 }
@@ -33,6 +33,37 @@ func TestNow(t *testing.T) {
 	}
 }
 
+func TestRun_nowWithTZ(t *testing.T) {
+	// --tz はローカルのタイムゾーンと違っても現在時刻をずらさない
+	nowInterface = nil
+	defer func() { nowInterface = &MyTime{} }()
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+
+	for _, tz := range []string{"America/New_York", "UTC"} {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+		args := []string{AppName, "--tz", tz, "-o", "RFC3339", "now"}
+		before := time.Now().Truncate(time.Second)
+		if status := clo.Run(args); status != ExitCodeOK {
+			t.Fatalf("Run(%s): ExitStatus = %d; want %d: %s", args, status, ExitCodeOK, errStream.String())
+		}
+		after := time.Now()
+
+		actual, err := time.Parse(time.RFC3339, strings.TrimSpace(outStream.String()))
+		if err != nil {
+			t.Fatalf("Run(%s): Output = %q: %v", args, outStream.String(), err)
+		}
+		if actual.Before(before) || actual.After(after) {
+			t.Errorf("Run(%s): Output = %v; want between %v and %v", args, actual, before, after)
+		}
+		loc, _ := time.LoadLocation(tz)
+		_, offset := actual.Zone()
+		if _, expect := actual.In(loc).Zone(); offset != expect {
+			t.Errorf("Run(%s): Output = %v; want offset of %s", args, actual, tz)
+		}
+	}
+}
+
 func TestRun_versionFlag(t *testing.T) {
 	params := []struct {
 		argstr string
@@ -91,8 +122,23 @@ func TestRun_add(t *testing.T) {
 		{args: []string{AppName, "2018/03/31 00:00:00", "+1M"}, expect: "2018/05/01 00:00:00"},
 		{args: []string{AppName, "-a", "2018/03/31 00:00:00", "+1M"}, expect: "2018/04/30 00:00:00"},
 		{args: []string{AppName, "--adjust-day", "2018/03/31 00:00:00", "+1M"}, expect: "2018/04/30 00:00:00"},
+		{args: []string{AppName, "2016/02/29 00:00:00", "+1Y"}, expect: "2017/03/01 00:00:00"},
+		{args: []string{AppName, "-a", "2016/02/29 00:00:00", "+1Y"}, expect: "2017/02/28 00:00:00"},
+		{args: []string{AppName, "--on-invalid-day", "clamp", "2016/02/29 00:00:00", "-1Y"}, expect: "2015/02/28 00:00:00"},
+		{args: []string{AppName, "-a", "--on-invalid-day", "normalize", "2016/02/29 00:00:00", "+1Y"}, expect: "2017/03/01 00:00:00"},
+		{args: []string{AppName, "--on-invalid-day", "error", "2016/02/29 00:00:00", "+4Y"}, expect: "2020/02/29 00:00:00"},
 
 		{args: []string{AppName, "2018/05/12 17:30:00", "+1D"}, expect: "2018/05/13 17:30:00"},
+		{args: []string{AppName, "--tz", "UTC", "-i", "gps", "-o", "RFC3339", "1167264018"}, expect: "2017-01-01T00:00:00Z"},
+		{args: []string{AppName, "--tz", "UTC", "-i", "gps", "-o", "RFC3339", "--show-leap-second", "1167264017"}, expect: "2016-12-31T23:59:60Z"},
+		{args: []string{AppName, "-o", "gps", "2017-01-01T00:00:00Z", "+1s"}, expect: "1167264019"},
+		{args: []string{AppName, "--tz", "UTC", "--scale", "tai", "2024-01-01T00:00:37Z"}, expect: "2024-01-01T00:00:00Z"},
+		{args: []string{AppName, "--tz", "UTC", "--output-scale", "gps", "2024-01-01T00:00:00Z"}, expect: "2024-01-01T00:00:18Z"},
+		{args: []string{AppName, "--tz", "America/New_York", "2024/03/09 12:00:00", "+1D"}, expect: "2024/03/10 12:00:00"},
+		{args: []string{AppName, "--tz", "America/New_York", "2024/03/09 12:00:00", "+24h"}, expect: "2024/03/10 13:00:00"},
+		{args: []string{AppName, "--tz", "America/New_York", "2024/03/10 02:30:00"}, expect: "2024/03/10 03:30:00"},
+		{args: []string{AppName, "--tz", "America/New_York", "--on-dst", "earlier", "2024/03/09 02:30:00", "+1D"}, expect: "2024/03/10 01:30:00"},
+		{args: []string{AppName, "--tz", "America/New_York", "--on-dst", "later", "-o", "15:04 MST", "2024/11/03 01:30:00"}, expect: "01:30 EST"},
 		{args: []string{AppName, "2018/05/12 17:30:00", "-1D"}, expect: "2018/05/11 17:30:00"},
 
 		{args: []string{AppName, "2018/05/12 17:30:00", "+1h"}, expect: "2018/05/12 18:30:00"},
@@ -107,6 +153,7 @@ func TestRun_add(t *testing.T) {
 		// 入力フォーマット
 		{args: []string{AppName, "now", "+1Y"}, expect: "2019/05/12 17:30:00"},
 		{args: []string{AppName, "1526113800", "+1Y"}, expect: "1557649800"},
+		{args: []string{AppName, "@1526113800", "+1Y"}, expect: "1557649800"},
 		{args: []string{AppName, "2018/05/12 17:30:00", "+1Y"}, expect: "2019/05/12 17:30:00"},
 		{args: []string{AppName, "2018-05-12 17:30:00", "+1Y"}, expect: "2019-05-12 17:30:00"},
 		{args: []string{AppName, "2018/05/12 17:30", "+1Y"}, expect: "2019/05/12 17:30"},
@@ -134,6 +181,24 @@ func TestRun_add(t *testing.T) {
 		{args: []string{AppName, "--output-format", "2006-01-02 15:04:05", "1526113800"}, expect: "2018-05-12 17:30:00"},
 		{args: []string{AppName, "--output-format", "ANSIC", "1526113800"}, expect: "Sat May 12 17:30:00 2018"},
 		{args: []string{AppName, "--input-format", "unixm", "--output-format", "unixm", "1526113800000"}, expect: "1526113800000"},
+		{args: []string{AppName, "-o", "ulid", "1526113800", "+1D"}, expect: "01CDC9XTT00000000000000000"},
+		{args: []string{AppName, "-o", "uuidv7", "1526113800", "-1D"}, expect: "01634e52-3340-7000-8000-000000000000"},
+
+		// 期間
+		{args: []string{AppName, "2018/05/12 17:30:00", "P1Y2M10DT2H30M"}, expect: "2019/07/22 20:00:00"},
+		{args: []string{AppName, "2018/05/12 17:30:00", "-P1W"}, expect: "2018/05/05 17:30:00"},
+		{args: []string{AppName, "2018/05/12 17:30:00", "1h30m15s"}, expect: "2018/05/12 19:00:15"},
+		{args: []string{AppName, "-a", "2018/03/31 00:00:00", "P1M"}, expect: "2018/04/30 00:00:00"},
+		{args: []string{AppName, "-o", "diff", "now", "+1D", "-90m"}, expect: "22h30m0s"},
+		{args: []string{AppName, "-o", "diff-iso", "--relative-to", "2018/01/15", "2018/03/01 12:00:00"}, expect: "P1M14DT12H"},
+
+		// 相対表記
+		{args: []string{AppName, "-o", "relative", "2018/05/09 17:30:00"}, expect: "3 days ago"},
+		{args: []string{AppName, "-o", "relative", "--lang", "ja", "now", "+2h"}, expect: "2時間後"},
+		{args: []string{AppName, "-o", "relative", "--relative-to", "2018/05/15 19:00:00", "--granularity", "2", "2018/05/12 17:30:00"}, expect: "3 days 1 hour ago"},
+		// 現在時刻の固定
+		{args: []string{AppName, "--now", "2024/02/29 12:00:00", "now", "+1D"}, expect: "2024/03/01 12:00:00"},
+		{args: []string{AppName, "--now", "2024/02/29 12:00:00", "-o", "relative", "2024/02/22 12:00:00"}, expect: "1 week ago"},
 
 		// タイムゾーン
 		{args: []string{AppName, "-i", "2006/01/02 15:04:05 MST", "-o", "15:04:05 MST", "2018/05/12 17:30:00 JST"}, expect: "17:30:00 JST"},
@@ -141,6 +206,8 @@ func TestRun_add(t *testing.T) {
 		{args: []string{AppName, "-i", "2006/01/02 15:04:05 MST", "-o", "15:04:05 MST", "2018/05/12 17:30:00"}, expect: "17:30:00 JST"},
 		{args: []string{AppName, "-i", "2006/01/02 15:04:05", "-o", "15:04:05 MST", "2018/05/12 17:30:00"}, expect: "17:30:00 JST"},
 		{args: []string{AppName, "-i", "unix", "-o", "15:04:05 MST", "1526113800"}, expect: "17:30:00 JST"},
+		{args: []string{AppName, "--tz", "UTC", "-o", "15:04:05 MST", "1526113800"}, expect: "08:30:00 UTC"},
+		{args: []string{AppName, "-z", "UTC", "-o", "unix", "2018/05/12 08:30:00"}, expect: "1526113800"},
 
 		// 引数がないときはシステム日付を出力
 		{args: []string{AppName}, expect: "2018/05/12 17:30:00"},
@@ -167,6 +234,10 @@ func TestRun_add(t *testing.T) {
 func TestRun_unixTime(t *testing.T) {
 	formats["us"] = unixSeconds
 	formats["um"] = unixMilliSeconds
+	defer func() {
+		delete(formats, "us")
+		delete(formats, "um")
+	}()
 
 	nowInterface = &MyTime{}
 	params := []struct {
@@ -196,6 +267,48 @@ func TestRun_unixTime(t *testing.T) {
 	}
 }
 
+func TestRun_sourceDateEpoch(t *testing.T) {
+	nowInterface = &MyTime{}
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	params := []struct {
+		args   []string
+		expect string
+	}{
+		{args: []string{AppName, "--tz", "UTC", "-o", "RFC3339", "now"}, expect: "2023-11-14T22:13:20Z"},
+		{args: []string{AppName, "-o", "relative", "2023-11-13T22:13:20Z"}, expect: "1 day ago"},
+		// --now が優先
+		{args: []string{AppName, "--now", "2018/05/12 17:30:00", "now"}, expect: "2018/05/12 17:30:00"},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+
+		args := p.args
+		status := clo.Run(args)
+		if status != ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want %d", args, status, ExitCodeOK)
+		}
+
+		actual := outStream.String()
+		expect := p.expect
+		if strings.Contains(actual, expect) == false {
+			t.Errorf("Run(%s): Output = %v; want %v", args, actual, expect)
+		}
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	clo := &CLO{outStream: outStream, errStream: errStream}
+	args := []string{AppName, "now"}
+	if status := clo.Run(args); status != ExitCodeError {
+		t.Errorf("Run(%s): ExitStatus = %d; want %d", args, status, ExitCodeError)
+	}
+	if expect := "SOURCE_DATE_EPOCH: 'yesterday' is invalid unix time."; strings.Contains(errStream.String(), expect) == false {
+		t.Errorf("Run(%s): Output = %v; want %v", args, errStream.String(), expect)
+	}
+}
+
 func TestRun_error(t *testing.T) {
 	nowInterface = &MyTime{}
 	params := []struct {
@@ -204,6 +317,19 @@ func TestRun_error(t *testing.T) {
 	}{
 		// 指定ミス: "Y" とすべきところを "y"
 		{args: []string{AppName, "now", "+1y"}, expect: "'+1y' is invalid format."},
+		{args: []string{AppName, "-o", "relative", "--rounding", "up", "now"}, expect: "'up' is invalid rounding."},
+		{args: []string{AppName, "--tz", "Mars/Olympus", "now"}, expect: "'Mars/Olympus' is invalid time zone."},
+		{args: []string{AppName, "--on-invalid-day", "error", "2016/02/29", "+1Y"}, expect: "'2017-02-29' is invalid date."},
+		{args: []string{AppName, "--on-invalid-day", "error", "2018/03/31", "+1M"}, expect: "'2018-04-31' is invalid date."},
+		{args: []string{AppName, "--on-invalid-day", "skip", "now"}, expect: "'skip' is invalid day policy."},
+		{args: []string{AppName, "--tz", "America/New_York", "--on-dst", "error", "2024/03/10 02:30:00"}, expect: "'2024-03-10 02:30:00' does not exist in America/New_York."},
+		{args: []string{AppName, "--tz", "America/New_York", "--on-dst", "error", "2024/11/02 01:30:00", "+1D"}, expect: "'2024-11-03 01:30:00' is ambiguous in America/New_York."},
+		{args: []string{AppName, "--on-dst", "skip", "now"}, expect: "'skip' is invalid DST policy."},
+		{args: []string{AppName, "--scale", "tt", "now"}, expect: "'tt' is invalid time scale."},
+		{args: []string{AppName, "-o", "snowflake:x:y", "now"}, expect: "'snowflake:x:y' is invalid format."},
+		{args: []string{AppName, "-o", "ksuid", "2014-01-01"}, expect: "is out of range for ksuid."},
+		{args: []string{AppName, "--now", "someday", "now"}, expect: "'someday' is invalid format."},
+		{args: []string{AppName, "--leap-seconds", "/nonexistent/leap-seconds.list", "now"}, expect: "/nonexistent/leap-seconds.list"},
 	}
 
 	for _, p := range params {
@@ -0,0 +1,732 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/pelletier/go-toml"
+	"github.com/urfave/cli"
+)
+
+const configFileName = ".dt"
+
+func configCommand() cli.Command {
+	return cli.Command{
+		Name:      "config",
+		Usage:     "設定を表示します",
+		UsageText: AppName + " config show [options]",
+		HideHelp:  true,
+		Subcommands: []cli.Command{
+			{
+				Name:      "show",
+				Usage:     "設定ファイルと環境変数とオプションをまとめた設定を表示します",
+				UsageText: AppName + " config show [options]",
+				Description: `システム (/etc/dt/.dt), ユーザー (~/.config/dt/.dt), プロジェクト (カレントディレクトリか親の .dt) の
+   設定ファイル, DT_TZ などの環境変数, コマンドラインのオプションの順に優先して, 設定ファイルの形式で表示します.`,
+				HideHelp: true,
+				Flags: commandFlags(
+					cli.BoolFlag{
+						Name:  "origin",
+						Usage: "値を設定したファイルと行番号, 環境変数, オプションのいずれかを表示します",
+					},
+				),
+				Action: commandAction(configShow),
+			},
+		},
+	}
+}
+
+// configDefaults [defaults] のキーと, 指定がないときに値を設定するオプション
+var configDefaults = map[string]string{
+	"input-format":   "input-format, i",
+	"output-format":  "output-format, o",
+	"zone":           "tz, z",
+	"on-invalid-day": "on-invalid-day",
+	"working-hours":  "working-hours",
+	"weekend":        "weekend",
+}
+
+// configSections 設定ファイルのテーブル
+var configSections = []string{"defaults", "formats", "macros", "zones", "holidays"}
+
+var (
+	formatsTableRegexp = regexp.MustCompile(`^\[\s*formats\s*\]\s*(#.*)?$`)
+	bareKeyRegexp      = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	// tomlErrorRegexp "(2, 8): ..." のような go-toml のエラー
+	tomlErrorRegexp = regexp.MustCompile(`^\((\d+), \d+\): (.*)$`)
+)
+
+// formatPriorities フォーマットの優先度. 入力のフォーマットを自動で判断するときは優先度の高い順に試します.
+var formatPriorities = map[string]int{}
+
+// macros 計算式のマクロ. 計算式の中の名前を定義した計算式に置き換えます.
+var macros = map[string]string{}
+
+// configEnvVars 環境変数と対応する [defaults] のキー
+var configEnvVars = map[string]string{
+	"DT_INPUT_FORMAT":   "input-format",
+	"DT_OUTPUT_FORMAT":  "output-format",
+	"DT_TZ":             "zone",
+	"DT_ON_INVALID_DAY": "on-invalid-day",
+	"DT_WEEK_START":     "week-start",
+	"DT_HOLIDAYS":       "holidays",
+}
+
+// systemConfigDir システム全体の設定ファイルを置くディレクトリ
+var systemConfigDir = "/etc/dt"
+
+// config システム, ユーザー, プロジェクトの設定ファイルと環境変数をまとめた設定
+var config = newConfigFile("")
+
+// configFile 設定ファイルの内容
+type configFile struct {
+	path       string
+	formats    map[string]string
+	priorities map[string]int
+	// defaults [defaults] の値. holidays 以外はここに入ります.
+	defaults map[string]string
+	holidays []string
+	macros   map[string]string
+	// zones [zones] のタイムゾーンのグループ
+	zones map[string][]string
+	// zoneHolidays [holidays] のタイムゾーンごとの祝日のファイル
+	zoneHolidays map[string][]string
+	// origins 値を設定したファイルと行番号か環境変数. キーは "defaults.zone" のようなセクションとキーです.
+	origins map[string]string
+}
+
+func newConfigFile(path string) *configFile {
+	return &configFile{
+		path:         path,
+		formats:      map[string]string{},
+		priorities:   map[string]int{},
+		defaults:     map[string]string{},
+		macros:       map[string]string{},
+		zones:        map[string][]string{},
+		zoneHolidays: map[string][]string{},
+		origins:      map[string]string{},
+	}
+}
+
+// loadConfig 設定ファイルと環境変数を読み, フォーマットとマクロと週の始まりを設定する.
+// path を指定したときはそのファイルだけを, noConfig のときは環境変数だけを読みます.
+func loadConfig(path string, noConfig bool) error {
+	config = newConfigFile("")
+	weekStart = time.Monday
+	macros = map[string]string{}
+
+	paths, err := configPaths(path, noConfig)
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		cf, err := readConfig(p)
+		if err != nil {
+			return err
+		}
+		log.Printf("config: %s", p)
+		config.merge(cf)
+	}
+	env, err := envConfig()
+	if err != nil {
+		return err
+	}
+	config.merge(env)
+
+	for k, v := range config.formats {
+		log.Printf("custom format: %s => %s\n", k, v)
+		formats[k] = v
+		formatPriorities[k] = config.priorities[k]
+	}
+	for k, v := range config.macros {
+		macros[k] = v
+	}
+	if s, ok := config.defaults["week-start"]; ok {
+		weekStart, _ = parseWeekStart(s)
+	}
+	return nil
+}
+
+// configPaths 読む設定ファイル. 優先度の低い順にシステム, ユーザー, プロジェクトの設定ファイルのうち存在するものです.
+// プロジェクトの設定ファイルはカレントディレクトリから親をたどって最初に見つかった .dt です.
+func configPaths(path string, noConfig bool) ([]string, error) {
+	if noConfig {
+		return nil, nil
+	}
+	if path != "" {
+		info, err := os.Stat(path)
+		switch {
+		case os.IsNotExist(err):
+			return nil, fmt.Errorf("'%s' is not found.", path)
+		case err != nil:
+			return nil, err
+		case info.IsDir():
+			return nil, fmt.Errorf("'%s' is a directory.", path)
+		}
+		return []string{path}, nil
+	}
+
+	var paths []string
+	candidates := []string{filepath.Join(systemConfigDir, configFileName)}
+	if dir, err := configDir(); err == nil {
+		candidates = append(candidates, filepath.Join(dir, configFileName))
+	}
+	if project := findProjectConfig(); project != "" {
+		candidates = append(candidates, project)
+	}
+	for _, p := range candidates {
+		if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() && containsString(paths, p) == false {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+func findProjectConfig() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		p := filepath.Join(dir, configFileName)
+		if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+			return p
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func readConfig(path string) (*configFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseConfig(path, f)
+}
+
+// envConfig DT_TZ などの環境変数を [defaults] の値にする. DT_HOLIDAYS はパスの区切り文字で複数指定できます.
+func envConfig() (*configFile, error) {
+	cf := newConfigFile("")
+	for name, key := range configEnvVars {
+		v, ok := os.LookupEnv(name)
+		if ok == false || v == "" {
+			continue
+		}
+		var value interface{} = v
+		if key == "holidays" {
+			value = filepath.SplitList(v)
+		}
+		if err := cf.setDefault(key, value); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		cf.origins["defaults."+key] = name
+	}
+	return cf, nil
+}
+
+// merge other の値で上書きする.
+func (cf *configFile) merge(other *configFile) {
+	for k, v := range other.formats {
+		cf.formats[k] = v
+		cf.priorities[k] = other.priorities[k]
+	}
+	for k, v := range other.defaults {
+		cf.defaults[k] = v
+	}
+	if len(other.holidays) > 0 {
+		cf.holidays = other.holidays
+	}
+	for k, v := range other.macros {
+		cf.macros[k] = v
+	}
+	for k, v := range other.zones {
+		cf.zones[k] = v
+	}
+	for k, v := range other.zoneHolidays {
+		cf.zoneHolidays[k] = v
+	}
+	for k, v := range other.origins {
+		cf.origins[k] = v
+	}
+}
+
+// applyConfigDefaults コマンドラインで指定していないオプションに設定ファイルの [defaults] の値を設定する.
+// 指定したオプションは設定の値として記録します.
+func applyConfigDefaults(c *cli.Context) error {
+	for key, names := range configDefaults {
+		name := strings.Split(names, ",")[0]
+		if c.IsSet(name) {
+			config.defaults[key] = c.String(name)
+			config.origins["defaults."+key] = "--" + name
+			continue
+		}
+		v, ok := config.defaults[key]
+		if ok == false {
+			continue
+		}
+		for _, name := range strings.Split(names, ",") {
+			if err := c.Set(strings.TrimSpace(name), v); err != nil {
+				return err
+			}
+		}
+	}
+	if c.IsSet("holidays") {
+		config.holidays = []string{c.String("holidays")}
+		config.origins["defaults.holidays"] = "--holidays"
+	}
+	return nil
+}
+
+// holidaySources 祝日のファイル. --holidays か設定の holidays で, どちらもなければ設定ディレクトリの holidays です.
+func holidaySources(c *cli.Context) []string {
+	if len(config.holidays) > 0 {
+		return config.holidays
+	}
+	return []string{""}
+}
+
+// parseConfig 設定ファイルを解釈する. 形式は TOML で, [defaults], [formats], [macros], [zones], [holidays] の
+// テーブルに値を書きます. 以前の形式との互換性のため, 最初のテーブルより前のキーは [formats] とみなし,
+// [formats] では TOML の文字列やインラインテーブルでない値も行末までの文字列として受け付けます.
+// 正しくない値は, ファイル名と行番号をつけたエラーにします.
+func parseConfig(path string, r io.Reader) (*configFile, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	lines := legacyConfigLines(strings.Split(string(b), "\n"))
+	tree, err := toml.Load(strings.Join(lines, "\n"))
+	if err != nil {
+		if m := tomlErrorRegexp.FindStringSubmatch(err.Error()); m != nil {
+			return nil, fmt.Errorf("%s:%s: %s", path, m[1], m[2])
+		}
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	cf := newConfigFile(path)
+	sections, sectionLines := configKeys(tree, lines, 0)
+	for _, section := range sections {
+		table, ok := tree.GetPath([]string{section}).(*toml.Tree)
+		if ok == false || containsString(configSections, section) == false {
+			return nil, fmt.Errorf("%s:%d: '%s' is unknown section.", path, sectionLines[section], section)
+		}
+		keys, keyLines := configKeys(table, lines, sectionLines[section])
+		for _, key := range keys {
+			if err := cf.set(section, key, configValue(table.GetPath([]string{key}))); err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, keyLines[key], err)
+			}
+			cf.origins[section+"."+key] = fmt.Sprintf("%s:%d", path, keyLines[key])
+		}
+	}
+	return cf, nil
+}
+
+// legacyConfigLines 以前の形式の "name = layout" の行を TOML の行にする. 最初のテーブルより前のキーは
+// formats.name にし, [formats] とあわせて TOML の文字列やインラインテーブルでない値を文字列にします.
+// 行番号が変わらないように, 1 行ずつ置き換えます.
+func legacyConfigLines(lines []string) []string {
+	result := make([]string, len(lines))
+	top, formats := true, true
+	for i, line := range lines {
+		result[i] = line
+		s := strings.TrimSpace(line)
+		if strings.HasPrefix(s, "[") {
+			top = false
+			formats = formatsTableRegexp.MatchString(s)
+			continue
+		}
+		if formats == false || s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		key, raw := splitFormat(s)
+		if key == "" || raw == "" {
+			continue
+		}
+		if strings.HasPrefix(key, `"`) == false && strings.HasPrefix(key, "'") == false {
+			key = quoteConfigKey(key)
+		}
+		if legacyLayout(raw) {
+			raw = strconv.Quote(raw)
+		}
+		if top {
+			key = "formats." + key
+		}
+		result[i] = key + " = " + raw
+	}
+	return result
+}
+
+// legacyLayout 値が TOML の文字列やインラインテーブルでない, 以前の形式のレイアウトのときは true を返す.
+// yearonly = 2006 のように整数や真偽値として解釈できるときもレイアウトです.
+func legacyLayout(raw string) bool {
+	for _, prefix := range []string{`"""`, "'''", "["} {
+		if strings.HasPrefix(raw, prefix) {
+			return false
+		}
+	}
+	tree, err := toml.Load("v = " + raw)
+	if err != nil {
+		return true
+	}
+	switch tree.GetPath([]string{"v"}).(type) {
+	case string, *toml.Tree:
+		return false
+	}
+	return true
+}
+
+// configKeys テーブルのキーを定義した行の順に返す. 行番号は 1 からです.
+// go-toml はインラインテーブルの行番号を記録しないので, そのときは from 行目より後でキーを定義した行を探します.
+func configKeys(t *toml.Tree, lines []string, from int) ([]string, map[string]int) {
+	keys := t.Keys()
+	keyLines := map[string]int{}
+	for _, key := range keys {
+		n := t.GetPositionPath([]string{key}).Line
+		for i := from; n == 0 && i < len(lines); i++ {
+			k, _ := splitFormat(strings.TrimSpace(lines[i]))
+			if unquoted, err := strconv.Unquote(k); err == nil {
+				k = unquoted
+			}
+			if k == key || strings.HasSuffix(k, "."+key) || strings.HasSuffix(k, "."+strconv.Quote(key)) {
+				n = i + 1
+			}
+		}
+		keyLines[key] = n
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keyLines[keys[i]] != keyLines[keys[j]] {
+			return keyLines[keys[i]] < keyLines[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys, keyLines
+}
+
+// configValue TOML の値を設定の値にする. 文字列の配列は []string に, テーブルは map にします.
+func configValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, e := range v {
+			s, ok := e.(string)
+			if ok == false {
+				return v
+			}
+			values[i] = s
+		}
+		return values
+	case *toml.Tree:
+		values := map[string]interface{}{}
+		for _, k := range v.Keys() {
+			values[k] = configValue(v.GetPath([]string{k}))
+		}
+		return values
+	}
+	return v
+}
+
+// set セクションのキーに値を設定する.
+func (cf *configFile) set(section, key string, v interface{}) error {
+	switch section {
+	case "formats":
+		return cf.setFormat(key, v)
+	case "macros":
+		s, ok := v.(string)
+		if ok == false || strings.TrimSpace(s) == "" {
+			return fmt.Errorf("macro '%s' needs an expression.", key)
+		}
+		if identRegexp.MatchString(key) == false {
+			return fmt.Errorf("'%s' is invalid macro name.", key)
+		}
+		cf.macros[key] = s
+		return nil
+	case "zones":
+		return cf.setZones(key, v)
+	case "holidays":
+		return cf.setZoneHolidays(key, v)
+	default:
+		return cf.setDefault(key, v)
+	}
+}
+
+// setFormat "name = layout" か "name = { layout = ..., priority = 10 }" のフォーマットを設定する.
+func (cf *configFile) setFormat(name string, v interface{}) error {
+	var layout string
+	var priority int
+	switch v := v.(type) {
+	case string:
+		layout = v
+	case map[string]interface{}:
+		for k, field := range v {
+			var ok bool
+			switch k {
+			case "layout":
+				layout, ok = field.(string)
+			case "priority":
+				var n int64
+				n, ok = field.(int64)
+				priority = int(n)
+			default:
+				return fmt.Errorf("'%s' is unknown key.", k)
+			}
+			if ok == false {
+				return fmt.Errorf("'%s' of format '%s' is invalid value.", k, name)
+			}
+		}
+	default:
+		return fmt.Errorf("format '%s' needs a layout.", name)
+	}
+	if layout == "" {
+		return fmt.Errorf("format '%s' needs a layout.", name)
+	}
+	cf.formats[name] = layout
+	cf.priorities[name] = priority
+	return nil
+}
+
+// setZones "name = ["Asia/Tokyo", "Europe/Berlin@08:00-17:00"]" のタイムゾーンのグループを設定する.
+func (cf *configFile) setZones(name string, v interface{}) error {
+	zones, ok := v.([]string)
+	if ok == false || len(zones) == 0 {
+		return fmt.Errorf("zone group '%s' needs time zones.", name)
+	}
+	for _, z := range zones {
+		if _, err := parseZoneEntry(z, workingHours{}); err != nil {
+			return err
+		}
+	}
+	cf.zones[name] = zones
+	return nil
+}
+
+// setZoneHolidays "Asia/Tokyo = "holidays-jp"" のようにタイムゾーンの祝日のファイルかその配列を設定する.
+func (cf *configFile) setZoneHolidays(name string, v interface{}) error {
+	var paths []string
+	switch v := v.(type) {
+	case string:
+		paths = []string{v}
+	case []string:
+		paths = v
+	default:
+		return fmt.Errorf("'%s' is invalid value.", name)
+	}
+	if _, err := loadLocation(name); err != nil {
+		return err
+	}
+	cf.zoneHolidays[name] = nil
+	for _, p := range paths {
+		cf.zoneHolidays[name] = append(cf.zoneHolidays[name], cf.resolvePath(p))
+	}
+	return nil
+}
+
+// setDefault [defaults] の値を検査して設定する.
+func (cf *configFile) setDefault(key string, v interface{}) error {
+	if key == "holidays" {
+		var paths []string
+		switch v := v.(type) {
+		case string:
+			paths = []string{v}
+		case []string:
+			paths = v
+		default:
+			return fmt.Errorf("'%s' is invalid value.", key)
+		}
+		for _, p := range paths {
+			cf.holidays = append(cf.holidays, cf.resolvePath(p))
+		}
+		return nil
+	}
+
+	s, ok := v.(string)
+	if ok == false {
+		return fmt.Errorf("'%s' is invalid value.", key)
+	}
+	var err error
+	switch key {
+	case "input-format", "output-format":
+	case "zone":
+		_, err = loadLocation(s)
+	case "on-invalid-day":
+		_, err = parseAdjustDay(s)
+	case "week-start":
+		_, err = parseWeekStart(s)
+	case "working-hours":
+		_, err = parseWorkingHours(s)
+	case "weekend":
+		_, err = parseWeekend(s)
+	default:
+		return fmt.Errorf("'%s' is unknown key.", key)
+	}
+	if err != nil {
+		return err
+	}
+	cf.defaults[key] = s
+	return nil
+}
+
+// resolvePath ~ を展開し, 相対パスは設定ファイルのディレクトリからのパスにする.
+func (cf *configFile) resolvePath(p string) string {
+	if expanded, err := homedir.Expand(p); err == nil {
+		p = expanded
+	}
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(filepath.Dir(cf.path), p)
+}
+
+// formatNames 入力のフォーマットを自動で判断するときに試す順のフォーマットの名前. 優先度の高い順,
+// 同じ優先度のときは名前の順です.
+func formatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		pi, pj := formatPriorities[names[i]], formatPriorities[names[j]]
+		if pi != pj {
+			return pi > pj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// expandMacros src の中のマクロの名前を定義した計算式に置き換える.
+func expandMacros(src string) (string, error) {
+	if len(macros) == 0 {
+		return src, nil
+	}
+	for depth := 0; ; depth++ {
+		var b strings.Builder
+		last := 0
+		var expanded []string
+		for _, t := range lex(src) {
+			m, ok := macros[t.text]
+			if t.kind != tokenWord || ok == false {
+				continue
+			}
+			b.WriteString(src[last:t.pos] + " " + m + " ")
+			last = t.end
+			expanded = append(expanded, t.text)
+		}
+		if len(expanded) == 0 {
+			return src, nil
+		}
+		if depth >= 10 {
+			return src, fmt.Errorf("macro '%s' is recursive.", expanded[0])
+		}
+		b.WriteString(src[last:])
+		src = strings.TrimSpace(b.String())
+	}
+}
+
+// configShow 設定を設定ファイルの形式で出力する.
+func configShow(c *cli.Context) error {
+	w := bufio.NewWriter(clo.outStream)
+	defer w.Flush()
+
+	line := func(key, value string) {
+		s := quoteConfigKey(key[strings.Index(key, ".")+1:]) + " = " + value
+		if c.Bool("origin") {
+			s += "  # " + config.origins[key]
+		}
+		fmt.Fprintln(w, s)
+	}
+	section := func(name string, keys []string) {
+		if len(keys) == 0 {
+			return
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(w, "[%s]\n", name)
+		for _, k := range keys {
+			switch name {
+			case "defaults":
+				if k == "holidays" {
+					line("defaults.holidays", quoteConfigArray(config.holidays))
+				} else {
+					line("defaults."+k, strconv.Quote(config.defaults[k]))
+				}
+			case "formats":
+				v := strconv.Quote(config.formats[k])
+				if p := config.priorities[k]; p != 0 {
+					v = fmt.Sprintf("{ layout = %s, priority = %d }", v, p)
+				}
+				line("formats."+k, v)
+			case "zones":
+				line("zones."+k, quoteConfigArray(config.zones[k]))
+			case "holidays":
+				line("holidays."+k, quoteConfigArray(config.zoneHolidays[k]))
+			default:
+				line("macros."+k, strconv.Quote(config.macros[k]))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	var keys []string
+	for k := range config.defaults {
+		keys = append(keys, k)
+	}
+	if len(config.holidays) > 0 {
+		keys = append(keys, "holidays")
+	}
+	section("defaults", keys)
+
+	keys = nil
+	for k := range config.formats {
+		keys = append(keys, k)
+	}
+	section("formats", keys)
+
+	keys = nil
+	for k := range config.macros {
+		keys = append(keys, k)
+	}
+	section("macros", keys)
+
+	keys = nil
+	for k := range config.zones {
+		keys = append(keys, k)
+	}
+	section("zones", keys)
+
+	keys = nil
+	for k := range config.zoneHolidays {
+		keys = append(keys, k)
+	}
+	section("holidays", keys)
+	return nil
+}
+
+func quoteConfigArray(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func quoteConfigKey(key string) string {
+	if bareKeyRegexp.MatchString(key) == false {
+		return strconv.Quote(key)
+	}
+	return key
+}
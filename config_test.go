@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	content := `# 以前の形式
+myformat = 02-Jan-06 15:04:05
+
+[defaults]
+output-format = "YMD-"  # コメント
+zone = 'UTC'
+week-start = "sunday"
+holidays = ["holidays", "/etc/dt/holidays"]
+
+[formats]
+"dmy" = { layout = "02/01/2006", priority = 10 }
+
+[macros]
+eom = "@endM"
+
+[formats.mdy]
+layout = "01/02/2006"
+priority = 5
+
+[zones]
+team = [
+  "Asia/Tokyo",
+  "Europe/Berlin@08:00-17:00",  # ベルリンは 8 時から
+]
+
+[holidays]
+"Europe/Berlin" = "holidays-de"
+`
+	cf, err := parseConfig("/home/user/.config/dt/.dt", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("parseConfig() = %v", err)
+	}
+
+	if expect := map[string]string{"myformat": "02-Jan-06 15:04:05", "dmy": "02/01/2006", "mdy": "01/02/2006"}; reflect.DeepEqual(cf.formats, expect) == false {
+		t.Errorf("formats = %v; want %v", cf.formats, expect)
+	}
+	if cf.priorities["dmy"] != 10 || cf.priorities["mdy"] != 5 || cf.priorities["myformat"] != 0 {
+		t.Errorf("priorities = %v", cf.priorities)
+	}
+	if expect := map[string]string{"output-format": "YMD-", "zone": "UTC", "week-start": "sunday"}; reflect.DeepEqual(cf.defaults, expect) == false {
+		t.Errorf("defaults = %v; want %v", cf.defaults, expect)
+	}
+	if expect := []string{"/home/user/.config/dt/holidays", "/etc/dt/holidays"}; reflect.DeepEqual(cf.holidays, expect) == false {
+		t.Errorf("holidays = %v; want %v", cf.holidays, expect)
+	}
+	if expect := map[string]string{"eom": "@endM"}; reflect.DeepEqual(cf.macros, expect) == false {
+		t.Errorf("macros = %v; want %v", cf.macros, expect)
+	}
+	if expect := map[string][]string{"team": {"Asia/Tokyo", "Europe/Berlin@08:00-17:00"}}; reflect.DeepEqual(cf.zones, expect) == false {
+		t.Errorf("zones = %v; want %v", cf.zones, expect)
+	}
+	if expect := map[string][]string{"Europe/Berlin": {"/home/user/.config/dt/holidays-de"}}; reflect.DeepEqual(cf.zoneHolidays, expect) == false {
+		t.Errorf("zoneHolidays = %v; want %v", cf.zoneHolidays, expect)
+	}
+	if expect := map[string]string{
+		"formats.myformat":       "/home/user/.config/dt/.dt:2",
+		"formats.dmy":            "/home/user/.config/dt/.dt:11",
+		"formats.mdy":            "/home/user/.config/dt/.dt:16",
+		"zones.team":             "/home/user/.config/dt/.dt:21",
+		"holidays.Europe/Berlin": "/home/user/.config/dt/.dt:27",
+	}; reflect.DeepEqual(map[string]string{
+		"formats.myformat":       cf.origins["formats.myformat"],
+		"formats.dmy":            cf.origins["formats.dmy"],
+		"formats.mdy":            cf.origins["formats.mdy"],
+		"zones.team":             cf.origins["zones.team"],
+		"holidays.Europe/Berlin": cf.origins["holidays.Europe/Berlin"],
+	}, expect) == false {
+		t.Errorf("origins = %v; want %v", cf.origins, expect)
+	}
+}
+
+func TestParseConfig_error(t *testing.T) {
+	params := []struct {
+		content string
+		expect  string
+	}{
+		{content: "[defaults]\nzone = \"Mars/Olympus\"\n", expect: ".dt:2: 'Mars/Olympus' is invalid time zone."},
+		{content: "[defaults]\nzone = Asia/Tokyo\n", expect: ".dt:2: no value can start with A"},
+		{content: "[defaults]\ncolor = \"always\"\n", expect: ".dt:2: 'color' is unknown key."},
+		{content: "[defaults]\non-invalid-day = \"skip\"\n", expect: ".dt:2: 'skip' is invalid day policy."},
+		{content: "\n[options]\n", expect: ".dt:2: 'options' is unknown section."},
+		{content: "a = 2006\na = 06\n", expect: ".dt:2: The following key was defined twice: formats.a"},
+		{content: "just a line\n", expect: ".dt:1: was expecting token =, but got \"a\" instead"},
+		{content: "[formats]\nx = { layout = \"2006\", priority = \"high\" }\n", expect: ".dt:2: 'priority' of format 'x' is invalid value."},
+		{content: "[macros]\nsprint = 2\n", expect: ".dt:2: macro 'sprint' needs an expression."},
+		{content: "[macros]\n\"next sprint\" = \"+2W\"\n", expect: ".dt:2: 'next sprint' is invalid macro name."},
+		{content: "[zones]\nteam = \"Asia/Tokyo\"\n", expect: ".dt:2: zone group 'team' needs time zones."},
+		{content: "[zones]\nteam = [\"Asia/Tokyo@9-5\"]\n", expect: ".dt:2: '9-5' is invalid working hours."},
+		{content: "[holidays]\n\"Mars/Olympus\" = \"holidays\"\n", expect: ".dt:2: 'Mars/Olympus' is invalid time zone."},
+		{content: "[defaults]\nworking-hours = \"18:00-18:00\"\n", expect: ".dt:2: '18:00-18:00' is invalid working hours."},
+		{content: "[defaults]\nweekend = \"fri/sat\"\n", expect: ".dt:2: 'fri/sat' is invalid weekend."},
+		{content: "[defaults]\nzone = \"UTC\"\n\n[formats]\ndmy = \"02/01/2006\"\nx = { layout = \"2006\", color = \"red\" }\n", expect: ".dt:6: 'color' is unknown key."},
+		{content: "[zones]\nteam = [\n  \"Asia/Tokyo\",\n  \"UTC\",\n\n[macros]\n", expect: ".dt:6: no value can start with m"},
+	}
+
+	for _, p := range params {
+		_, err := parseConfig(".dt", strings.NewReader(p.content))
+		if err == nil || err.Error() != p.expect {
+			t.Errorf("parseConfig(%q) = %v; want %s", p.content, err, p.expect)
+		}
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	saved := macros
+	defer func() { macros = saved }()
+	macros = map[string]string{"eom": "@endM", "sprint": "+2W", "release": "sprint sprint", "loop": "loop"}
+
+	params := []struct {
+		src    string
+		expect string
+	}{
+		{src: "now eom", expect: "now  @endM"},
+		{src: "sprint", expect: "+2W"},
+		{src: "release", expect: "+2W   +2W"},
+		{src: "eomx", expect: "eomx"},
+	}
+	for _, p := range params {
+		actual, err := expandMacros(p.src)
+		if err != nil || actual != p.expect {
+			t.Errorf("expandMacros(%q) = %q, %v; want %q", p.src, actual, err, p.expect)
+		}
+	}
+	if _, err := expandMacros("loop"); err == nil {
+		t.Errorf("expandMacros(%q) = nil; want error", "loop")
+	}
+}
+
+func TestRun_config(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if err := os.Mkdir(filepath.Join(dir, "dt"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := `[defaults]
+output-format = "YMD-"
+week-start = "sunday"
+
+[formats]
+mdy = { layout = "01/02/2006", priority = 10 }
+dmy = "02/01/2006"
+
+[macros]
+eom = "@endM"
+sprint = "+2W"
+
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "dt", ".dt"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		delete(formats, "mdy")
+		delete(formats, "dmy")
+		formatPriorities = map[string]int{}
+	}()
+
+	params := []struct {
+		args   []string
+		expect string
+	}{
+		{args: []string{AppName, "2024-03-05", "eom"}, expect: "2024-03-31\n"},
+		{args: []string{AppName, "2024-03-05 sprint sprint"}, expect: "2024-04-02\n"},
+		{args: []string{AppName, "-o", "def", "03/04/2024"}, expect: "2024/03/04 00:00:00\n"},
+		{args: []string{AppName, "2024-03-06", "@startW"}, expect: "2024-03-03\n"},
+	}
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+		if status := clo.Run(p.args); status != ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != p.expect {
+			t.Errorf("Run(%s): Output = %q; want %q", p.args, outStream.String(), p.expect)
+		}
+	}
+}
+
+func TestRun_configShow(t *testing.T) {
+	root := t.TempDir()
+	for _, d := range []string{"etc", "user/dt", "project/sub"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		"etc/.dt":          "[defaults]\nzone = \"UTC\"\noutput-format = \"def\"\n[macros]\neom = \"@endM\"\n",
+		"user/dt/.dt":      "myformat = 02-Jan-06\n[defaults]\noutput-format = \"YMD-\"\n",
+		"project/.dt":      "[defaults]\nholidays = \"holidays\"\n[formats]\np = { layout = \"2006.01.02\", priority = 5 }\n",
+		"other.dt":         "[macros]\nsprint = \"+2W\"\n",
+		"project/holidays": "2024-01-01 元日\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	savedDir := systemConfigDir
+	systemConfigDir = filepath.Join(root, "etc")
+	wd, _ := os.Getwd()
+	if err := os.Chdir(filepath.Join(root, "project", "sub")); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		systemConfigDir = savedDir
+		os.Chdir(wd)
+		delete(formats, "myformat")
+		delete(formats, "p")
+		formatPriorities = map[string]int{}
+	}()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "user"))
+	t.Setenv("DT_TZ", "Asia/Tokyo")
+	// macOS では一時ディレクトリがシンボリックリンクなので, カレントディレクトリから見たパスにする
+	project, _ := filepath.EvalSymlinks(filepath.Join(root, "project"))
+
+	params := []struct {
+		args   []string
+		expect string
+	}{
+		{
+			args: []string{AppName, "config", "show", "--origin"},
+			expect: "[defaults]\n" +
+				"holidays = [\"" + filepath.Join(project, "holidays") + "\"]  # " + filepath.Join(project, ".dt") + ":2\n" +
+				"output-format = \"YMD-\"  # " + filepath.Join(root, "user", "dt", ".dt") + ":3\n" +
+				"zone = \"Asia/Tokyo\"  # DT_TZ\n" +
+				"\n" +
+				"[formats]\n" +
+				"myformat = \"02-Jan-06\"  # " + filepath.Join(root, "user", "dt", ".dt") + ":1\n" +
+				"p = { layout = \"2006.01.02\", priority = 5 }  # " + filepath.Join(project, ".dt") + ":4\n" +
+				"\n" +
+				"[macros]\n" +
+				"eom = \"@endM\"  # " + filepath.Join(root, "etc", ".dt") + ":5\n" +
+				"\n",
+		},
+		{
+			args:   []string{AppName, "config", "show", "--no-config", "--origin", "-o", "RFC3339"},
+			expect: "[defaults]\noutput-format = \"RFC3339\"  # --output-format\nzone = \"Asia/Tokyo\"  # DT_TZ\n\n",
+		},
+		{
+			args:   []string{AppName, "config", "show", "--config", filepath.Join(root, "other.dt")},
+			expect: "[defaults]\nzone = \"Asia/Tokyo\"\n\n[macros]\nsprint = \"+2W\"\n\n",
+		},
+		{args: []string{AppName, "2024.01.05", "eom"}, expect: "2024-01-31\n"},
+		{args: []string{AppName, "-o", "RFC3339", "2024.01.05"}, expect: "2024-01-05T00:00:00+09:00\n"},
+	}
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+		if status := clo.Run(p.args); status != ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != p.expect {
+			t.Errorf("Run(%s): Output = %q; want %q", p.args, outStream.String(), p.expect)
+		}
+	}
+
+	for path, expect := range map[string]string{
+		filepath.Join(root, "missing.dt"): "'" + filepath.Join(root, "missing.dt") + "' is not found.",
+		root:                              "'" + root + "' is a directory.",
+	} {
+		errStream := new(bytes.Buffer)
+		if status := (&CLO{outStream: new(bytes.Buffer), errStream: errStream}).Run([]string{AppName, "--config", path, "now"}); status != ExitCodeError {
+			t.Errorf("Run(--config %s): ExitStatus = %d; want %d", path, status, ExitCodeError)
+		}
+		if strings.Contains(errStream.String(), expect) == false {
+			t.Errorf("Run(--config %s): Output = %q; want %q", path, errStream.String(), expect)
+		}
+	}
+
+	t.Setenv("DT_TZ", "Mars/Olympus")
+	errStream := new(bytes.Buffer)
+	if status := (&CLO{outStream: new(bytes.Buffer), errStream: errStream}).Run([]string{AppName, "now"}); status != ExitCodeError {
+		t.Errorf("Run(DT_TZ=Mars/Olympus): ExitStatus = %d; want %d", status, ExitCodeError)
+	}
+	if expect := "DT_TZ: 'Mars/Olympus' is invalid time zone."; strings.Contains(errStream.String(), expect) == false {
+		t.Errorf("Run(DT_TZ=Mars/Olympus): Output = %q; want %q", errStream.String(), expect)
+	}
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// cronSearchYears 次の実行日時を探す年数. これより先に実行日時がないときはエラーです.
+const cronSearchYears = 400
+
+// cronMacros @daily などの実行日時の別名
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	cronMonthNames   = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+	cronWeekdayNames = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+)
+
+func cronCommand() cli.Command {
+	return cli.Command{
+		Name:      "cron",
+		Usage:     "cron 式の実行日時を計算します",
+		UsageText: AppName + " cron [options] 'cron expression' [date [expr [expr ...]]]",
+		Description: `計算した日時 (デフォルトは現在時刻) の後か前の cron 式の実行日時を出力します.
+   5 つのフィールド (分 時 日 月 曜日) か, 先頭に秒を加えた 6 つのフィールド, @daily などの別名を指定できます.
+   日の L (月末), L-3, 15W (15 日に最も近い平日), LW と曜日の 5L (最後の金曜日), 1#2 (第 2 月曜日) も使えます.
+   先頭の CRON_TZ=Asia/Tokyo でタイムゾーンを指定します.`,
+		HideHelp: true,
+		Flags: commandFlags(
+			cli.IntFlag{
+				Name:  "next",
+				Usage: "計算した日時より後の実行日時を指定した数だけ出力します (デフォルトは 1)",
+			},
+			cli.IntFlag{
+				Name:  "prev",
+				Usage: "計算した日時より前の実行日時を新しい順に指定した数だけ出力します",
+			},
+		),
+		Action: commandAction(cron),
+	}
+}
+
+// cronSchedule cron 式
+type cronSchedule struct {
+	seconds, minutes, hours, months uint64
+	// days 日のフィールド. daysAny のときは制限しません.
+	days    []cronDay
+	daysAny bool
+	// weekdays 曜日のフィールド. weekdaysAny のときは制限しません.
+	weekdays    []cronWeekday
+	weekdaysAny bool
+	loc         *time.Location
+}
+
+// cronDay 日のフィールドのひとつの要素
+type cronDay struct {
+	set uint64
+	// last 月末から offset 日前
+	last   bool
+	offset int
+	// weekday day に最も近い平日. last と一緒のときは月末の最後の平日です.
+	weekday bool
+	day     int
+}
+
+// cronWeekday 曜日のフィールドのひとつの要素
+type cronWeekday struct {
+	set uint64
+	// last その月の最後の weekday
+	last    bool
+	weekday time.Weekday
+	// nth その月の第 nth の weekday
+	nth int
+}
+
+func cron(c *cli.Context) error {
+	if len(c.Args()) == 0 {
+		return errors.New("cron needs a cron expression.")
+	}
+	if c.IsSet("next") && c.IsSet("prev") {
+		return errors.New("--next and --prev cannot be used together.")
+	}
+	count, forward := 1, true
+	switch {
+	case c.IsSet("prev"):
+		count, forward = c.Int("prev"), false
+	case c.IsSet("next"):
+		count = c.Int("next")
+	}
+	if count <= 0 {
+		return fmt.Errorf("'%d' is invalid count.", count)
+	}
+
+	v, err := evalArgs(c, c.Args()[1:])
+	if err != nil {
+		return err
+	}
+	if v.dt == nil {
+		return evalError(errors.New("cron needs a date."))
+	}
+	schedule, err := parseCron(c.Args()[0], v.dt.time.Location())
+	if err != nil {
+		return err
+	}
+
+	t := v.dt.time
+	var dts []*Dt
+	for i := 0; i < count; i++ {
+		var ok bool
+		if forward {
+			t, ok = schedule.next(t)
+		} else {
+			t, ok = schedule.prev(t)
+		}
+		if ok == false {
+			if i == 0 {
+				return evalError(fmt.Errorf("'%s' has no fire times.", c.Args()[0]))
+			}
+			break
+		}
+		dts = append(dts, &Dt{time: t, format: v.dt.format})
+	}
+	return outputAll(c.Args()[0], dts)
+}
+
+// parseCron cron 式を解析する. CRON_TZ= か TZ= でタイムゾーンを指定しないときは loc で計算します.
+func parseCron(expr string, loc *time.Location) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "CRON_TZ=") || strings.HasPrefix(fields[0], "TZ=")) {
+		var err error
+		if loc, err = loadLocation(fields[0][strings.Index(fields[0], "=")+1:]); err != nil {
+			return nil, err
+		}
+		fields = fields[1:]
+	}
+	if len(fields) == 1 && strings.HasPrefix(fields[0], "@") {
+		macro, ok := cronMacros[strings.ToLower(fields[0])]
+		if ok == false {
+			return nil, fmt.Errorf("'%s' is invalid cron expression.", expr)
+		}
+		fields = strings.Fields(macro)
+	}
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("'%s' is invalid cron expression.", expr)
+	}
+
+	s := &cronSchedule{loc: loc}
+	var err error
+	if s.seconds, err = parseCronSet(fields[0], 0, 59, nil, "second"); err != nil {
+		return nil, err
+	}
+	if s.minutes, err = parseCronSet(fields[1], 0, 59, nil, "minute"); err != nil {
+		return nil, err
+	}
+	if s.hours, err = parseCronSet(fields[2], 0, 23, nil, "hour"); err != nil {
+		return nil, err
+	}
+	if s.days, s.daysAny, err = parseCronDays(fields[3]); err != nil {
+		return nil, err
+	}
+	if s.months, err = parseCronSet(fields[4], 1, 12, cronMonthNames, "month"); err != nil {
+		return nil, err
+	}
+	if s.weekdays, s.weekdaysAny, err = parseCronWeekdays(fields[5]); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseCronSet "*", "1,3", "1-5", "*/15", "10-50/10" のようなフィールドを解析する.
+// names は min からの名前で, 大文字と小文字を区別しません.
+func parseCronSet(field string, min, max int, names []string, kind string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("'%s' is invalid %s field.", field, kind)
+			}
+			rng, step = part[:i], n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.Contains(rng, "-"):
+			i := strings.Index(rng, "-")
+			var err1, err2 error
+			lo, err1 = parseCronValue(rng[:i], min, max, names)
+			hi, err2 = parseCronValue(rng[i+1:], min, max, names)
+			if err1 != nil || err2 != nil || lo > hi {
+				return 0, fmt.Errorf("'%s' is invalid %s field.", field, kind)
+			}
+		default:
+			var err error
+			if lo, err = parseCronValue(rng, min, max, names); err != nil {
+				return 0, fmt.Errorf("'%s' is invalid %s field.", field, kind)
+			}
+			// 5/15 は 5 から最大値まで
+			if step == 1 {
+				hi = lo
+			}
+		}
+		for n := lo; n <= hi; n += step {
+			set |= 1 << uint(n)
+		}
+	}
+	return set, nil
+}
+
+func parseCronValue(s string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(s, name) {
+			return min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("'%s' is out of range.", s)
+	}
+	return n, nil
+}
+
+// parseCronDays 日のフィールドを解析する. L, L-3, 15W, LW も使えます.
+func parseCronDays(field string) ([]cronDay, bool, error) {
+	if field == "*" || field == "?" {
+		return nil, true, nil
+	}
+	invalid := fmt.Errorf("'%s' is invalid day field.", field)
+	var days []cronDay
+	for _, part := range strings.Split(field, ",") {
+		switch {
+		case part == "L":
+			days = append(days, cronDay{last: true})
+		case part == "LW":
+			days = append(days, cronDay{last: true, weekday: true})
+		case strings.HasPrefix(part, "L-"):
+			n, err := strconv.Atoi(part[2:])
+			if err != nil || n < 0 || n > 30 {
+				return nil, false, invalid
+			}
+			days = append(days, cronDay{last: true, offset: n})
+		case strings.HasSuffix(part, "W"):
+			n, err := strconv.Atoi(strings.TrimSuffix(part, "W"))
+			if err != nil || n < 1 || n > 31 {
+				return nil, false, invalid
+			}
+			days = append(days, cronDay{weekday: true, day: n})
+		default:
+			set, err := parseCronSet(part, 1, 31, nil, "day")
+			if err != nil {
+				return nil, false, invalid
+			}
+			days = append(days, cronDay{set: set})
+		}
+	}
+	return days, false, nil
+}
+
+// parseCronWeekdays 曜日のフィールドを解析する. 0 と 7 は日曜日です. 5L, 1#2 も使えます.
+func parseCronWeekdays(field string) ([]cronWeekday, bool, error) {
+	if field == "*" || field == "?" {
+		return nil, true, nil
+	}
+	invalid := fmt.Errorf("'%s' is invalid weekday field.", field)
+	var weekdays []cronWeekday
+	for _, part := range strings.Split(field, ",") {
+		switch {
+		case len(part) > 1 && strings.HasSuffix(part, "L"):
+			wd, err := parseCronValue(strings.TrimSuffix(part, "L"), 0, 7, cronWeekdayNames)
+			if err != nil {
+				return nil, false, invalid
+			}
+			weekdays = append(weekdays, cronWeekday{last: true, weekday: time.Weekday(wd % 7)})
+		case strings.Contains(part, "#"):
+			i := strings.Index(part, "#")
+			wd, err1 := parseCronValue(part[:i], 0, 7, cronWeekdayNames)
+			n, err2 := strconv.Atoi(part[i+1:])
+			if err1 != nil || err2 != nil || n < 1 || n > 5 {
+				return nil, false, invalid
+			}
+			weekdays = append(weekdays, cronWeekday{weekday: time.Weekday(wd % 7), nth: n})
+		default:
+			set, err := parseCronSet(part, 0, 7, cronWeekdayNames, "weekday")
+			if err != nil {
+				return nil, false, invalid
+			}
+			// 7 は日曜日
+			if set&(1<<7) != 0 {
+				set = set&^(1<<7) | 1
+			}
+			weekdays = append(weekdays, cronWeekday{set: set})
+		}
+	}
+	return weekdays, false, nil
+}
+
+// matchDate 日付が実行する日のときは true を返す. 日と曜日の両方を指定したときはどちらかに一致する日です.
+func (s *cronSchedule) matchDate(year int, month time.Month, day int) bool {
+	if s.months&(1<<uint(month)) == 0 {
+		return false
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	matchDays := s.daysAny
+	for _, d := range s.days {
+		if d.match(date, last) {
+			matchDays = true
+		}
+	}
+	matchWeekdays := s.weekdaysAny
+	for _, w := range s.weekdays {
+		if w.match(date, last) {
+			matchWeekdays = true
+		}
+	}
+	if s.daysAny == false && s.weekdaysAny == false {
+		return matchDays || matchWeekdays
+	}
+	return matchDays && matchWeekdays
+}
+
+func (d cronDay) match(date time.Time, last int) bool {
+	day := date.Day()
+	switch {
+	case d.weekday:
+		target := d.day
+		if d.last {
+			target = last
+		}
+		if target > last {
+			return false
+		}
+		return day == nearestWeekday(date.Year(), date.Month(), target, last)
+	case d.last:
+		return day == last-d.offset
+	default:
+		return d.set&(1<<uint(day)) != 0
+	}
+}
+
+// nearestWeekday その月の day に最も近い平日. 月をまたぎません.
+func nearestWeekday(year int, month time.Month, day, last int) int {
+	switch time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return day + 2
+		}
+		return day - 1
+	case time.Sunday:
+		if day == last {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
+
+func (w cronWeekday) match(date time.Time, last int) bool {
+	switch {
+	case w.last:
+		return date.Weekday() == w.weekday && date.Day()+7 > last
+	case w.nth > 0:
+		return date.Weekday() == w.weekday && (date.Day()-1)/7+1 == w.nth
+	default:
+		return w.set&(1<<uint(date.Weekday())) != 0
+	}
+}
+
+// next t より後の最初の実行日時. 夏時間の切り替えで存在しない時刻は実行せず, 2 回ある時刻は 1 回だけ実行します.
+func (s *cronSchedule) next(t time.Time) (time.Time, bool) {
+	return s.search(t, 1)
+}
+
+// prev t より前の最後の実行日時
+func (s *cronSchedule) prev(t time.Time) (time.Time, bool) {
+	return s.search(t, -1)
+}
+
+// search t から dir の向きに日ごとに探す.
+func (s *cronSchedule) search(t time.Time, dir int) (time.Time, bool) {
+	local := t.In(s.loc)
+	year, month, day := local.Date()
+	for i := 0; i < cronSearchYears*366; i++ {
+		date := time.Date(year, month, day+i*dir, 0, 0, 0, 0, time.UTC)
+		if s.matchDate(date.Year(), date.Month(), date.Day()) == false {
+			continue
+		}
+		if found, ok := s.searchDay(date, t, dir); ok {
+			return found, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// searchDay date の日の実行時刻のうち, t より dir の向きで最も近いもの.
+func (s *cronSchedule) searchDay(date, t time.Time, dir int) (time.Time, bool) {
+	for hi := 0; hi < 24; hi++ {
+		h := cronIndex(hi, 23, dir)
+		if s.hours&(1<<uint(h)) == 0 {
+			continue
+		}
+		for mi := 0; mi < 60; mi++ {
+			m := cronIndex(mi, 59, dir)
+			if s.minutes&(1<<uint(m)) == 0 {
+				continue
+			}
+			for si := 0; si < 60; si++ {
+				sec := cronIndex(si, 59, dir)
+				if s.seconds&(1<<uint(sec)) == 0 {
+					continue
+				}
+				c := time.Date(date.Year(), date.Month(), date.Day(), h, m, sec, 0, s.loc)
+				if c.Hour() != h || c.Minute() != m {
+					// 夏時間の切り替えで存在しない時刻
+					continue
+				}
+				if (dir > 0 && c.After(t)) || (dir < 0 && c.Before(t)) {
+					return c, true
+				}
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// cronIndex dir が負のときは max から逆順にする.
+func cronIndex(i, max, dir int) int {
+	if dir < 0 {
+		return max - i
+	}
+	return i
+}
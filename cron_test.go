@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronSchedule_next(t *testing.T) {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	params := []struct {
+		expr   string
+		expect []string
+	}{
+		{expr: "0 9 * * 1-5", expect: []string{"2024-03-04T09:00:00Z", "2024-03-05T09:00:00Z"}},
+		{expr: "*/20 * * * * *", expect: []string{"2024-03-01T10:00:20Z", "2024-03-01T10:00:40Z"}},
+		{expr: "@monthly", expect: []string{"2024-04-01T00:00:00Z", "2024-05-01T00:00:00Z"}},
+		{expr: "0 12 L * ?", expect: []string{"2024-03-31T12:00:00Z", "2024-04-30T12:00:00Z"}},
+		{expr: "0 0 L-1 * *", expect: []string{"2024-03-30T00:00:00Z", "2024-04-29T00:00:00Z"}},
+		{expr: "0 0 1W * *", expect: []string{"2024-04-01T00:00:00Z", "2024-05-01T00:00:00Z", "2024-06-03T00:00:00Z"}},
+		{expr: "0 0 LW * *", expect: []string{"2024-03-29T00:00:00Z", "2024-04-30T00:00:00Z"}},
+		{expr: "0 0 * * FRIL", expect: []string{"2024-03-29T00:00:00Z", "2024-04-26T00:00:00Z"}},
+		{expr: "0 0 * * 1#2", expect: []string{"2024-03-11T00:00:00Z", "2024-04-08T00:00:00Z"}},
+		{expr: "0 0 * * 7", expect: []string{"2024-03-03T00:00:00Z"}},
+		{expr: "0 0 1,15 * MON", expect: []string{"2024-03-04T00:00:00Z", "2024-03-11T00:00:00Z", "2024-03-15T00:00:00Z"}},
+		{expr: "0 0 29 feb *", expect: []string{"2028-02-29T00:00:00Z"}},
+		{expr: "5/20 0 1 1 *", expect: []string{"2025-01-01T00:05:00Z", "2025-01-01T00:25:00Z", "2025-01-01T00:45:00Z"}},
+		// 夏時間の開始日の 2:30 は存在しない
+		{expr: "CRON_TZ=America/New_York 30 2 * * *", expect: []string{"2024-03-02T07:30:00Z"}},
+	}
+
+	for _, p := range params {
+		s, err := parseCron(p.expr, time.UTC)
+		if err != nil {
+			t.Fatalf("parseCron(%q) = %v", p.expr, err)
+		}
+		at := base
+		for _, expect := range p.expect {
+			var ok bool
+			at, ok = s.next(at)
+			if actual := at.UTC().Format(time.RFC3339); ok == false || actual != expect {
+				t.Errorf("parseCron(%q).next() = %s, %v; want %s", p.expr, actual, ok, expect)
+				break
+			}
+		}
+	}
+
+	s, _ := parseCron("CRON_TZ=America/New_York 30 2 * * *", time.UTC)
+	at, _ := s.next(time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC))
+	if expect := "2024-03-11T02:30:00-04:00"; at.Format(time.RFC3339) != expect {
+		t.Errorf("next() = %v; want %s", at.Format(time.RFC3339), expect)
+	}
+}
+
+func TestCronSchedule_prev(t *testing.T) {
+	s, err := parseCron("0 0 * * 1-5", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	for _, expect := range []string{"2024-03-01T00:00:00Z", "2024-02-29T00:00:00Z"} {
+		at, _ = s.prev(at)
+		if actual := at.Format(time.RFC3339); actual != expect {
+			t.Errorf("prev() = %s; want %s", actual, expect)
+		}
+	}
+}
+
+func TestParseCron_error(t *testing.T) {
+	params := []struct {
+		expr   string
+		expect string
+	}{
+		{expr: "* * * *", expect: "'* * * *' is invalid cron expression."},
+		{expr: "@reboot", expect: "'@reboot' is invalid cron expression."},
+		{expr: "61 * * * *", expect: "'61' is invalid minute field."},
+		{expr: "* 5-1 * * *", expect: "'5-1' is invalid hour field."},
+		{expr: "* * 32W * *", expect: "'32W' is invalid day field."},
+		{expr: "* * * 13 *", expect: "'13' is invalid month field."},
+		{expr: "* * * * MON#6", expect: "'MON#6' is invalid weekday field."},
+		{expr: "*/0 * * * * *", expect: "'*/0' is invalid second field."},
+		{expr: "CRON_TZ=Mars/Olympus * * * * *", expect: "'Mars/Olympus' is invalid time zone."},
+	}
+	for _, p := range params {
+		if _, err := parseCron(p.expr, time.UTC); err == nil || err.Error() != p.expect {
+			t.Errorf("parseCron(%q) = %v; want %s", p.expr, err, p.expect)
+		}
+	}
+}
+
+func TestRun_cron(t *testing.T) {
+	nowInterface = &MyTime{}
+	params := []struct {
+		args   []string
+		expect string
+	}{
+		{args: []string{AppName, "cron", "0 9 * * 1-5", "--next", "3", "2024-03-01 10:00:00"}, expect: "2024-03-04 09:00:00\n2024-03-05 09:00:00\n2024-03-06 09:00:00\n"},
+		{args: []string{AppName, "cron", "--prev", "2", "@daily", "2024-03-01", "+12h"}, expect: "2024-03-01\n2024-02-29\n"},
+		{args: []string{AppName, "cron", "0 18 * * *", "-o", "relative"}, expect: "in 30 minutes\n"},
+		// サブコマンドの前と後のどちらのオプションも使う. 両方のときは後を優先する
+		{args: []string{AppName, "--now", "2024/03/08 10:00:00", "cron", "0 9 * * 1-5", "--next", "2"}, expect: "2024/03/11 09:00:00\n2024/03/12 09:00:00\n"},
+		{args: []string{AppName, "cron", "0 9 * * 1-5", "--next", "2", "--now", "2024/03/08 10:00:00"}, expect: "2024/03/11 09:00:00\n2024/03/12 09:00:00\n"},
+		{args: []string{AppName, "-o", "RFC3339", "--tz", "UTC", "cron", "@daily", "--tz", "Asia/Tokyo", "2024-03-01"}, expect: "2024-03-02T00:00:00+09:00\n"},
+		{args: []string{AppName, "cron", "CRON_TZ=UTC 0 0 * * *", "-o", "RFC3339", "2024-03-01T10:00:00+09:00"}, expect: "2024-03-02T00:00:00Z\n"},
+	}
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+		if status := clo.Run(p.args); status != ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != p.expect {
+			t.Errorf("Run(%s): Output = %q; want %q", p.args, outStream.String(), p.expect)
+		}
+	}
+
+	errors := []struct {
+		args   []string
+		expect string
+	}{
+		{args: []string{AppName, "cron"}, expect: "cron needs a cron expression."},
+		{args: []string{AppName, "cron", "--next", "1", "--prev", "1", "@daily"}, expect: "--next and --prev cannot be used together."},
+		{args: []string{AppName, "cron", "--next", "0", "@daily"}, expect: "'0' is invalid count."},
+		{args: []string{AppName, "cron", "0 0 30 2 *"}, expect: "'0 0 30 2 *' has no fire times."},
+		{args: []string{AppName, "cron", "@daily", "1D"}, expect: "cron needs a date."},
+	}
+	for _, p := range errors {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+		if status := clo.Run(p.args); status == ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want error", p.args, status)
+		}
+		if strings.Contains(errStream.String(), p.expect) == false {
+			t.Errorf("Run(%s): Error = %v; want %v", p.args, errStream.String(), p.expect)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+func csvCommand() cli.Command {
+	return cli.Command{
+		Name:      "csv",
+		Usage:     "CSV や TSV の列の日時を変換します",
+		UsageText: AppName + " csv [options]",
+		Description: `標準入力の CSV を読み, --column の列の日時を --expr の計算式と -o のフォーマットで変換して出力します.
+   変換しない列は入力のまま出力します. 空の値は変換しません.`,
+		HideHelp: true,
+		Flags: commandFlags(
+			cli.StringSliceFlag{
+				Name:  "column, c",
+				Usage: "変換する列の名前か 1 から数えた番号を指定します. 複数指定できます",
+			},
+			cli.StringSliceFlag{
+				Name:  "expr, e",
+				Usage: "日時に続けて評価する計算式を指定します. 複数指定できます",
+			},
+			cli.StringFlag{
+				Name:  "header",
+				Value: "auto",
+				Usage: "1 行目がヘッダーかを指定します (auto, yes, no). auto のときは列を名前で指定したときか, 1 行目の値が日時でないときにヘッダーとみなします",
+			},
+			cli.StringFlag{
+				Name:  "new-column",
+				Usage: "変換した値を置き換えずに, この名前の列として末尾に追加します",
+			},
+			cli.StringFlag{
+				Name:  "delimiter, F",
+				Value: ",",
+				Usage: "区切り文字を指定します",
+			},
+			cli.BoolFlag{
+				Name:  "tsv",
+				Usage: "TSV として読み書きします. -F '\\t' と同じです",
+			},
+		),
+		Action: commandAction(csvTransform),
+	}
+}
+
+// csvField CSV のひとつの値. raw は引用符を含む入力のままの文字列
+type csvField struct {
+	raw   string
+	value string
+}
+
+// csvRecord CSV の 1 行. 引用符の中の改行を含むことがあります.
+type csvRecord struct {
+	fields []csvField
+	eol    string
+}
+
+// csvReader 入力のままの文字列を保ったまま CSV を 1 行ずつ読む.
+type csvReader struct {
+	reader    *bufio.Reader
+	delimiter byte
+}
+
+func (r *csvReader) read() (csvRecord, error) {
+	var text string
+	for {
+		line, err := r.reader.ReadString('\n')
+		text += line
+		// 引用符が閉じていなければ改行は値の一部
+		if err == nil && strings.Count(text, `"`)%2 == 1 {
+			continue
+		}
+		if err == io.EOF && text != "" {
+			err = nil
+		}
+		if err != nil {
+			return csvRecord{}, err
+		}
+		break
+	}
+
+	var rec csvRecord
+	switch {
+	case strings.HasSuffix(text, "\r\n"):
+		rec.eol = "\r\n"
+	case strings.HasSuffix(text, "\n"):
+		rec.eol = "\n"
+	}
+	text = text[:len(text)-len(rec.eol)]
+
+	for {
+		i := r.fieldEnd(text)
+		rec.fields = append(rec.fields, csvField{raw: text[:i], value: unquoteCSV(text[:i])})
+		if i == len(text) {
+			return rec, nil
+		}
+		text = text[i+1:]
+	}
+}
+
+// fieldEnd 最初の値の終わりの位置. 引用符の中の区切り文字は飛ばします.
+func (r *csvReader) fieldEnd(text string) int {
+	quoted := false
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '"':
+			quoted = !quoted
+		case text[i] == r.delimiter && quoted == false:
+			return i
+		}
+	}
+	return len(text)
+}
+
+func unquoteCSV(raw string) string {
+	if len(raw) < 2 || raw[0] != '"' || raw[len(raw)-1] != '"' {
+		return raw
+	}
+	return strings.ReplaceAll(raw[1:len(raw)-1], `""`, `"`)
+}
+
+func quoteCSV(s string, delimiter byte) string {
+	if strings.ContainsAny(s, "\"\r\n"+string(delimiter)) == false {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func (rec csvRecord) write(w io.Writer, delimiter byte) error {
+	raws := make([]string, len(rec.fields))
+	for i, f := range rec.fields {
+		raws[i] = f.raw
+	}
+	_, err := io.WriteString(w, strings.Join(raws, string(delimiter))+rec.eol)
+	return err
+}
+
+// csvTransformer 列の日時を変換する
+type csvTransformer struct {
+	columns   []int
+	exprs     []string
+	newColumn string
+	delimiter byte
+}
+
+func csvTransform(c *cli.Context) error {
+	delimiter := c.String("delimiter")
+	if c.Bool("tsv") {
+		delimiter = "\t"
+	}
+	if len(delimiter) != 1 {
+		return fmt.Errorf("'%s' is invalid delimiter.", delimiter)
+	}
+	specs := splitColumns(c.StringSlice("column"))
+	if len(specs) == 0 {
+		return errors.New("csv needs --column.")
+	}
+	t := &csvTransformer{exprs: c.StringSlice("expr"), newColumn: c.String("new-column"), delimiter: delimiter[0]}
+	if t.newColumn != "" && len(specs) != 1 {
+		return errors.New("--new-column needs exactly one --column.")
+	}
+
+	reader := &csvReader{reader: bufio.NewReaderSize(clo.inStream, 64*1024), delimiter: t.delimiter}
+	writer := bufio.NewWriterSize(clo.outStream, 64*1024)
+	defer writer.Flush()
+
+	first, err := reader.read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	header, err := t.resolve(specs, first, c.String("header"))
+	if err != nil {
+		return err
+	}
+	n := 1
+	if header {
+		if t.newColumn != "" {
+			first.fields = append(first.fields, csvField{raw: quoteCSV(t.newColumn, t.delimiter), value: t.newColumn})
+		}
+		if err := first.write(writer, t.delimiter); err != nil {
+			return err
+		}
+	} else if err := t.transform(first, n, writer); err != nil {
+		return err
+	}
+
+	for {
+		rec, err := reader.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		n++
+		if err := t.transform(rec, n, writer); err != nil {
+			return err
+		}
+	}
+}
+
+// splitColumns "a,b" のようにまとめて指定した列を分ける.
+func splitColumns(values []string) []string {
+	var result []string
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				result = append(result, s)
+			}
+		}
+	}
+	return result
+}
+
+// resolve 列の指定を 0 から数えた番号にする. 1 行目がヘッダーのときは true を返します.
+func (t *csvTransformer) resolve(specs []string, first csvRecord, mode string) (bool, error) {
+	byName := false
+	for _, s := range specs {
+		if _, err := strconv.Atoi(s); err != nil {
+			byName = true
+		}
+	}
+
+	var header bool
+	switch mode {
+	case "yes":
+		header = true
+	case "no":
+		if byName {
+			return false, errors.New("column names need a header.")
+		}
+	case "auto":
+		header = byName
+	default:
+		return false, fmt.Errorf("'%s' is invalid header.", mode)
+	}
+
+	for _, s := range specs {
+		if i, err := strconv.Atoi(s); err == nil {
+			if i < 1 {
+				return false, fmt.Errorf("'%s' is invalid column.", s)
+			}
+			t.columns = append(t.columns, i-1)
+			continue
+		}
+		found := false
+		for i, f := range first.fields {
+			if f.value == s {
+				t.columns = append(t.columns, i)
+				found = true
+				break
+			}
+		}
+		if found == false {
+			return false, fmt.Errorf("'%s' is not a column.", s)
+		}
+	}
+
+	// 番号で指定したときは 1 行目の値が日時でなければヘッダーとみなす
+	if mode == "auto" && header == false {
+		for _, i := range t.columns {
+			if i < len(first.fields) && first.fields[i].value != "" {
+				if _, err := processFirst(first.fields[i].value); err != nil {
+					header = true
+				}
+			}
+		}
+	}
+	return header, nil
+}
+
+// transform 1 行の列を変換して書く. n はエラーのときに表示するレコードの番号.
+func (t *csvTransformer) transform(rec csvRecord, n int, w io.Writer) error {
+	var added string
+	for _, i := range t.columns {
+		if i >= len(rec.fields) || rec.fields[i].value == "" {
+			continue
+		}
+		s, err := t.convert(rec.fields[i].value)
+		if err != nil {
+			return fmt.Errorf("record %d, column %d: %v", n, i+1, err)
+		}
+		if t.newColumn != "" {
+			added = s
+			continue
+		}
+		rec.fields[i] = csvField{raw: quoteCSV(s, t.delimiter), value: s}
+	}
+	if t.newColumn != "" {
+		rec.fields = append(rec.fields, csvField{raw: quoteCSV(added, t.delimiter), value: added})
+	}
+	return rec.write(w, t.delimiter)
+}
+
+func (t *csvTransformer) convert(s string) (string, error) {
+	return transformDate(s, t.exprs)
+}
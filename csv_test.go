@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCSVReader(t *testing.T) {
+	input := "a,\"b,c\",\"d\"\"e\"\r\n\"multi\nline\",,x\nlast"
+	r := &csvReader{reader: bufio.NewReader(strings.NewReader(input)), delimiter: ','}
+
+	expect := [][]string{
+		{"a", "b,c", `d"e`},
+		{"multi\nline", "", "x"},
+		{"last"},
+	}
+	for _, e := range expect {
+		rec, err := r.read()
+		if err != nil {
+			t.Fatalf("read() = %v", err)
+		}
+		var values []string
+		for _, f := range rec.fields {
+			values = append(values, f.value)
+		}
+		if strings.Join(values, "|") != strings.Join(e, "|") {
+			t.Errorf("read() = %q; want %q", values, e)
+		}
+	}
+}
+
+func TestRun_csv(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	input := "id,\"created_at\",note\r\n" +
+		"1,2024-01-31 23:30:00,\"hello, \"\"world\"\"\"\r\n" +
+		"2,,\"multi\r\nline\"\r\n" +
+		"3,2024-02-01 00:00:00,  spaced  \r\n"
+
+	params := []struct {
+		args   []string
+		input  string
+		expect string
+	}{
+		{
+			args:  []string{"--tz", "Asia/Tokyo", "-c", "created_at", "--expr", "+9h", "-o", "RFC3339"},
+			input: input,
+			expect: "id,\"created_at\",note\r\n" +
+				"1,2024-02-01T08:30:00+09:00,\"hello, \"\"world\"\"\"\r\n" +
+				"2,,\"multi\r\nline\"\r\n" +
+				"3,2024-02-01T09:00:00+09:00,  spaced  \r\n",
+		},
+		{
+			args:  []string{"-c", "2", "--new-column", "prev day", "--expr=-1D"},
+			input: input,
+			expect: "id,\"created_at\",note,prev day\r\n" +
+				"1,2024-01-31 23:30:00,\"hello, \"\"world\"\"\",2024-01-30 23:30:00\r\n" +
+				"2,,\"multi\r\nline\",\r\n" +
+				"3,2024-02-01 00:00:00,  spaced  ,2024-01-31 00:00:00\r\n",
+		},
+		{
+			args:   []string{"--tsv", "-c", "1,3", "-o", "Jan 2, 2006"},
+			input:  "2024-01-31\tx\t2024-02-01\n",
+			expect: "Jan 31, 2024\tx\tFeb 1, 2024\n",
+		},
+		{
+			args:   []string{"-c", "1", "-o", "Jan 2, 2006"},
+			input:  "2024-01-31,x",
+			expect: "\"Jan 31, 2024\",x",
+		},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{inStream: strings.NewReader(p.input), outStream: outStream, errStream: errStream}
+
+		status := clo.Run(append([]string{AppName, "csv"}, p.args...))
+		if status != ExitCodeOK {
+			t.Fatalf("Run(%v): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream)
+		}
+		if outStream.String() != p.expect {
+			t.Errorf("Run(%v): Output = %q; want %q", p.args, outStream, p.expect)
+		}
+	}
+}
+
+func TestRun_csvError(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	params := []struct {
+		args   []string
+		expect string
+	}{
+		{args: []string{}, expect: "csv needs --column."},
+		{args: []string{"-c", "missing"}, expect: "'missing' is not a column."},
+		{args: []string{"-c", "1", "--header", "no"}, expect: "record 1, column 1: 'created' is invalid format."},
+		{args: []string{"-c", "1,2", "--new-column", "x"}, expect: "--new-column needs exactly one --column."},
+		{args: []string{"-c", "1", "-F", "::"}, expect: "'::' is invalid delimiter."},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{inStream: strings.NewReader("created\n2024-01-31\n"), outStream: outStream, errStream: errStream}
+
+		status := clo.Run(append([]string{AppName, "csv"}, p.args...))
+		if status != ExitCodeError {
+			t.Errorf("Run(%v): ExitStatus = %d; want %d", p.args, status, ExitCodeError)
+		}
+		if strings.Contains(errStream.String(), p.expect) == false {
+			t.Errorf("Run(%v): Error = %q; want %q", p.args, errStream, p.expect)
+		}
+	}
+}
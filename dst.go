@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DSTPolicy 夏時間の切り替えで存在しない時刻 (gap) や 2 回ある時刻 (overlap) の扱い
+type DSTPolicy int
+
+const (
+	// DSTCompatible gap のときは切り替え前の時差で (遅い方), overlap のときは先の時刻 (早い方) を使います
+	DSTCompatible DSTPolicy = iota
+	// DSTEarlier gap でも overlap でも早い方の時刻を使います
+	DSTEarlier
+	// DSTLater gap でも overlap でも遅い方の時刻を使います
+	DSTLater
+	// DSTError gap や overlap になったときはエラーになります
+	DSTError
+)
+
+// dstPolicy 日付の計算や解析で gap や overlap になったときの扱い
+var dstPolicy = DSTCompatible
+
+// warnDST 夏時間の切り替えをまたいだときや gap, overlap になったときに警告を出力するか
+var warnDST = false
+
+// dstError --on-dst error のときに gap や overlap になったエラー
+type dstError string
+
+func (e dstError) Error() string {
+	return string(e)
+}
+
+// parseDSTPolicy --on-dst の値を DSTPolicy にする.
+func parseDSTPolicy(s string) (DSTPolicy, error) {
+	switch s {
+	case "compatible":
+		return DSTCompatible, nil
+	case "earlier":
+		return DSTEarlier, nil
+	case "later":
+		return DSTLater, nil
+	case "error":
+		return DSTError, nil
+	default:
+		return DSTCompatible, fmt.Errorf("'%s' is invalid DST policy.", s)
+	}
+}
+
+// localDate time.Date と同じだが, 夏時間の切り替えで存在しない時刻や 2 回ある時刻を dstPolicy に従って決める.
+func localDate(year int, month time.Month, day, hour, min, sec, nsec int, loc *time.Location) (time.Time, error) {
+	wall := time.Date(year, month, day, hour, min, sec, nsec, time.UTC)
+
+	// 前後 1 日の時差を候補にする. 切り替えの前の時差と後の時差になります.
+	var offsets [2]int
+	var candidates []time.Time
+	for i, probe := range []time.Time{wall.AddDate(0, 0, -1), wall.AddDate(0, 0, 1)} {
+		_, offsets[i] = probe.In(loc).Zone()
+		t := wall.Add(-time.Duration(offsets[i]) * time.Second).In(loc)
+		if sameWallClock(t, wall) && (len(candidates) == 0 || candidates[0].Equal(t) == false) {
+			candidates = append(candidates, t)
+		}
+	}
+
+	text := wall.Format("2006-01-02 15:04:05")
+	var earlier, later time.Time
+	var problem string
+	switch len(candidates) {
+	case 1:
+		return candidates[0], nil
+	case 2:
+		earlier, later = candidates[0], candidates[1]
+		problem = fmt.Sprintf("'%s' is ambiguous in %s", text, loc)
+	default:
+		earlier = wall.Add(-time.Duration(offsets[1]) * time.Second).In(loc)
+		later = wall.Add(-time.Duration(offsets[0]) * time.Second).In(loc)
+		if later.Before(earlier) {
+			earlier, later = later, earlier
+		}
+		problem = fmt.Sprintf("'%s' does not exist in %s", text, loc)
+	}
+	if dstPolicy == DSTError {
+		return time.Time{}, dstError(problem + ".")
+	}
+	dstWarnf("%s", problem)
+
+	gap := len(candidates) == 0
+	if dstPolicy == DSTLater || (dstPolicy == DSTCompatible && gap) {
+		return later, nil
+	}
+	return earlier, nil
+}
+
+func sameWallClock(t, wall time.Time) bool {
+	y, m, d := t.Date()
+	return y == wall.Year() && m == wall.Month() && d == wall.Day() &&
+		t.Hour() == wall.Hour() && t.Minute() == wall.Minute() && t.Second() == wall.Second()
+}
+
+// checkDSTCrossing from から to への計算が夏時間の切り替えをまたいだときに警告する.
+func checkDSTCrossing(step string, from, to time.Time) {
+	if warnDST == false {
+		return
+	}
+	_, before := from.Zone()
+	_, after := to.In(from.Location()).Zone()
+	if before != after {
+		dstWarnf("'%s' crossed a DST transition in %s (elapsed %v)", step, from.Location(), to.Sub(from))
+	}
+}
+
+// warnMu serve で同時に処理するリクエストの警告を 1 行ずつ出力する
+var warnMu sync.Mutex
+
+func dstWarnf(format string, args ...interface{}) {
+	if warnDST {
+		warnMu.Lock()
+		defer warnMu.Unlock()
+		fmt.Fprintf(clo.errStream, "warning: "+format+".\n", args...)
+	}
+}
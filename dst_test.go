@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestLocalDate(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer func() { dstPolicy = DSTCompatible }()
+
+	params := []struct {
+		policy DSTPolicy
+		wall   [4]int // 月, 日, 時, 分
+		expect string // UTC
+	}{
+		{policy: DSTCompatible, wall: [4]int{7, 1, 12, 0}, expect: "2024-07-01T16:00:00Z"},
+		// 2024-03-10 02:00 EST に 03:00 EDT になる
+		{policy: DSTCompatible, wall: [4]int{3, 10, 2, 30}, expect: "2024-03-10T07:30:00Z"},
+		{policy: DSTEarlier, wall: [4]int{3, 10, 2, 30}, expect: "2024-03-10T06:30:00Z"},
+		{policy: DSTLater, wall: [4]int{3, 10, 2, 30}, expect: "2024-03-10T07:30:00Z"},
+		// 2024-11-03 02:00 EDT に 01:00 EST になる
+		{policy: DSTCompatible, wall: [4]int{11, 3, 1, 30}, expect: "2024-11-03T05:30:00Z"},
+		{policy: DSTEarlier, wall: [4]int{11, 3, 1, 30}, expect: "2024-11-03T05:30:00Z"},
+		{policy: DSTLater, wall: [4]int{11, 3, 1, 30}, expect: "2024-11-03T06:30:00Z"},
+		{policy: DSTError, wall: [4]int{11, 3, 2, 0}, expect: "2024-11-03T07:00:00Z"},
+	}
+
+	for _, p := range params {
+		dstPolicy = p.policy
+		actual, err := localDate(2024, time.Month(p.wall[0]), p.wall[1], p.wall[2], p.wall[3], 0, 0, ny)
+		if err != nil {
+			t.Errorf("localDate(%v) policy %d: error = %v", p.wall, p.policy, err)
+			continue
+		}
+		if s := actual.UTC().Format(time.RFC3339); s != p.expect {
+			t.Errorf("localDate(%v) policy %d = %s; want %s", p.wall, p.policy, s, p.expect)
+		}
+	}
+
+	dstPolicy = DSTError
+	for _, wall := range [][4]int{{3, 10, 2, 30}, {11, 3, 1, 30}} {
+		if _, err := localDate(2024, time.Month(wall[0]), wall[1], wall[2], wall[3], 0, 0, ny); err == nil {
+			t.Errorf("localDate(%v) policy error: want error", wall)
+		}
+	}
+}
+
+func TestCheckDSTCrossing(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	errStream := new(bytes.Buffer)
+	saved := clo
+	clo, warnDST = &CLO{errStream: errStream}, true
+	defer func() { clo, warnDST = saved, false }()
+
+	from := time.Date(2024, 3, 9, 12, 0, 0, 0, ny)
+	checkDSTCrossing("+1h", from, from.Add(time.Hour))
+	if errStream.Len() != 0 {
+		t.Errorf("checkDSTCrossing() = %q; want no warning", errStream.String())
+	}
+
+	checkDSTCrossing("+1D", from, from.AddDate(0, 0, 1))
+	expect := "warning: '+1D' crossed a DST transition in America/New_York (elapsed 23h0m0s).\n"
+	if errStream.String() != expect {
+		t.Errorf("checkDSTCrossing() = %q; want %q", errStream.String(), expect)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// diffFormat 基準日時との差を Go の time.Duration 形式 (1h30m0s) で出力する
+	diffFormat = "diff"
+	// diffISOFormat 基準日時との差を ISO 8601 の期間形式 (P1DT2H) で出力する
+	diffISOFormat = "diff-iso"
+)
+
+var isoDurationRegexp = regexp.MustCompile(`^([-+])?P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+(?:[.,]\d+)?)H)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+
+// Duration 暦の部分 (年月日) と時刻の部分をもつ期間.
+// 暦の部分は AddDate で, 時刻の部分は Add で加算します.
+type Duration struct {
+	Years  int
+	Months int
+	Days   int
+	Clock  time.Duration
+}
+
+// Negate 符号を反転した期間を返す.
+func (d Duration) Negate() Duration {
+	return Duration{Years: -d.Years, Months: -d.Months, Days: -d.Days, Clock: -d.Clock}
+}
+
+// AddDuration 期間を加算. 暦の部分を先に加算してから時刻の部分を加算します.
+// 年月日はまとめて加算するので, 同じ日が存在しないときや夏時間の切り替えの調整は 1 回だけです.
+func (dt *Dt) AddDuration(d Duration, adjust AdjustDay) (*Dt, error) {
+	result, err := dt.addDate(12*d.Years+d.Months, d.Days, adjust)
+	if err != nil {
+		return dt, err
+	}
+	return &Dt{
+		time:   result.time.Add(d.Clock),
+		format: dt.format,
+	}, nil
+}
+
+// parseDuration ISO 8601 の期間 (P1Y2M10DT2H30M) か Go 形式の期間 (1h30m15s) を解析する.
+// 先頭に - をつけると負の期間になります.
+// ISO 8601 の期間は最後の時, 分, 秒に小数 (PT1.5H) を使えます. 年月週日の小数は長さが決まらないので使えません.
+func parseDuration(s string) (Duration, bool) {
+	if d, ok := parseISODuration(s); ok {
+		return d, true
+	}
+
+	clock, err := time.ParseDuration(s)
+	if err != nil {
+		return Duration{}, false
+	}
+	return Duration{Clock: clock}, true
+}
+
+func parseISODuration(s string) (Duration, bool) {
+	m := isoDurationRegexp.FindStringSubmatch(s)
+	if m == nil || strings.HasSuffix(s, "P") || strings.HasSuffix(s, "T") {
+		return Duration{}, false
+	}
+
+	atoi := func(v string) int {
+		i, _ := strconv.Atoi(v)
+		return i
+	}
+	d := Duration{
+		Years:  atoi(m[2]),
+		Months: atoi(m[3]),
+		Days:   atoi(m[4])*7 + atoi(m[5]),
+	}
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	for i, v := range m[6:9] {
+		if v == "" {
+			continue
+		}
+		// 小数を使えるのは最後の部分だけ
+		if strings.ContainsAny(v, ".,") && strings.Join(m[7+i:9], "") != "" {
+			return Duration{}, false
+		}
+		f, err := strconv.ParseFloat(strings.Replace(v, ",", ".", 1), 64)
+		if err != nil {
+			return Duration{}, false
+		}
+		d.Clock += time.Duration(f * float64(units[i]))
+	}
+
+	if m[1] == "-" {
+		return d.Negate(), true
+	}
+	return d, true
+}
+
+// durationBetween from から to までの期間を暦の部分と時刻の部分に分けて求める.
+func durationBetween(from, to time.Time) Duration {
+	if to.Before(from) {
+		return durationBetween(to, from).Negate()
+	}
+
+	months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month())
+	for months > 0 && from.AddDate(0, months, 0).After(to) {
+		months--
+	}
+	t := from.AddDate(0, months, 0)
+
+	days := int(to.Sub(t) / (24 * time.Hour))
+	for days > 0 && t.AddDate(0, 0, days).After(to) {
+		days--
+	}
+	for !t.AddDate(0, 0, days+1).After(to) {
+		days++
+	}
+	t = t.AddDate(0, 0, days)
+
+	return Duration{Years: months / 12, Months: months % 12, Days: days, Clock: to.Sub(t)}
+}
+
+// ISOString ISO 8601 の期間形式で表す.
+func (d Duration) ISOString() string {
+	if d.Years < 0 || d.Months < 0 || d.Days < 0 || d.Clock < 0 {
+		return "-" + d.Negate().ISOString()
+	}
+
+	var b strings.Builder
+	b.WriteString("P")
+	for _, p := range []struct {
+		n    int
+		unit string
+	}{{d.Years, "Y"}, {d.Months, "M"}, {d.Days, "D"}} {
+		if p.n != 0 {
+			fmt.Fprintf(&b, "%d%s", p.n, p.unit)
+		}
+	}
+
+	clock := d.Clock
+	if clock == 0 {
+		if b.Len() == 1 {
+			return "PT0S"
+		}
+		return b.String()
+	}
+	b.WriteString("T")
+	if h := clock / time.Hour; h != 0 {
+		fmt.Fprintf(&b, "%dH", h)
+		clock -= h * time.Hour
+	}
+	if m := clock / time.Minute; m != 0 {
+		fmt.Fprintf(&b, "%dM", m)
+		clock -= m * time.Minute
+	}
+	if clock != 0 {
+		b.WriteString(strconv.FormatFloat(clock.Seconds(), 'f', -1, 64) + "S")
+	}
+	return b.String()
+}
+
+// parsePositiveDuration ISO 8601 か Go 形式の正の期間を読む. 年と月は使えません.
+func parsePositiveDuration(s string) (time.Duration, error) {
+	d, ok := parseDuration(s)
+	if ok == false || d.Years != 0 || d.Months != 0 {
+		return 0, fmt.Errorf("'%s' is invalid duration.", s)
+	}
+	result := time.Duration(d.Days)*24*time.Hour + d.Clock
+	if result <= 0 {
+		return 0, fmt.Errorf("'%s' is invalid duration.", s)
+	}
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	params := []struct {
+		input  string
+		expect Duration
+	}{
+		{input: "P1Y2M10DT2H30M", expect: Duration{Years: 1, Months: 2, Days: 10, Clock: 2*time.Hour + 30*time.Minute}},
+		{input: "P2W", expect: Duration{Days: 14}},
+		{input: "PT1.5S", expect: Duration{Clock: 1500 * time.Millisecond}},
+		{input: "PT1.5H", expect: Duration{Clock: 90 * time.Minute}},
+		{input: "PT0,5M", expect: Duration{Clock: 30 * time.Second}},
+		{input: "P1DT2H1.5M", expect: Duration{Days: 1, Clock: 2*time.Hour + 90*time.Second}},
+		{input: "-P1D", expect: Duration{Days: -1}},
+		{input: "+PT90M", expect: Duration{Clock: 90 * time.Minute}},
+		{input: "1h30m15s", expect: Duration{Clock: time.Hour + 30*time.Minute + 15*time.Second}},
+		{input: "-90m", expect: Duration{Clock: -90 * time.Minute}},
+	}
+
+	for _, p := range params {
+		actual, ok := parseDuration(p.input)
+		if !ok || actual != p.expect {
+			t.Errorf("parseDuration(%s) = %+v, %v; want %+v", p.input, actual, ok, p.expect)
+		}
+	}
+}
+
+func TestParseDuration_invalid(t *testing.T) {
+	params := []string{"P", "PT", "P1DT", "1Y2", "1d", "", "PT1.5H30M", "PT0.5M1S", "P1.5D", "P0.5Y"}
+
+	for _, p := range params {
+		if actual, ok := parseDuration(p); ok {
+			t.Errorf("parseDuration(%s) = %+v, true; want false", p, actual)
+		}
+	}
+}
+
+func TestDt_AddDuration(t *testing.T) {
+	params := []struct {
+		initial  time.Time
+		duration Duration
+		adjust   AdjustDay
+		expect   time.Time
+	}{
+		{initial: createTime(2018, 1, 31), duration: Duration{Months: 1, Clock: time.Hour}, adjust: Normalize, expect: createTime(2018, 3, 3).Add(time.Hour)},
+		{initial: createTime(2018, 1, 31), duration: Duration{Months: 1, Clock: time.Hour}, adjust: AdjustToEndOfMonth, expect: createTime(2018, 2, 28).Add(time.Hour)},
+		{initial: createTime(2018, 1, 31), duration: Duration{Years: -1, Days: 1}, adjust: Normalize, expect: createTime(2017, 2, 1)},
+		{initial: createTime(2024, 2, 29), duration: Duration{Years: 1, Months: 1}, adjust: ErrorOnInvalidDay, expect: createTime(2025, 3, 29)},
+	}
+
+	for _, p := range params {
+		dt := &Dt{time: p.initial}
+
+		actual, err := dt.AddDuration(p.duration, p.adjust)
+		if err != nil || actual.get() != p.expect {
+			t.Errorf("Dt.AddDuration(%+v) = %v, %v, want %v", p.duration, actual.get(), err, p.expect)
+		}
+	}
+}
+
+func TestDurationBetween(t *testing.T) {
+	params := []struct {
+		from   time.Time
+		to     time.Time
+		expect string
+	}{
+		{from: createTime(2018, 5, 12), to: createTime(2018, 5, 12), expect: "PT0S"},
+		{from: createTime(2018, 5, 12), to: createTime(2019, 7, 22).Add(2*time.Hour + 30*time.Minute), expect: "P1Y2M10DT2H30M"},
+		{from: createTime(2018, 1, 15), to: createTime(2018, 3, 1), expect: "P1M14D"},
+		{from: createTime(2018, 1, 31), to: createTime(2018, 3, 1), expect: "P29D"},
+		{from: createTime(2018, 5, 12), to: createTime(2018, 5, 11).Add(1500 * time.Millisecond), expect: "-PT23H59M58.5S"},
+	}
+
+	for _, p := range params {
+		actual := durationBetween(p.from, p.to).ISOString()
+		if actual != p.expect {
+			t.Errorf("durationBetween(%v, %v) = %s, want %s", p.from, p.to, actual, p.expect)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import "errors"
+
+// ErrorKind エラーの種類. HTTP サーバーではステータスコードに対応します.
+type ErrorKind int
+
+const (
+	// ParseError 日時や計算式, オプションを解釈できない
+	ParseError ErrorKind = iota
+	// EvalError 解釈はできたが計算や出力ができない
+	EvalError
+)
+
+func (k ErrorKind) String() string {
+	if k == EvalError {
+		return "eval"
+	}
+	return "parse"
+}
+
+// kindError 種類をもつエラー
+type kindError struct {
+	kind ErrorKind
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+func evalError(err error) error {
+	return &kindError{kind: EvalError, err: err}
+}
+
+// errorKind エラーの種類を返す. 種類をもたないエラーは ParseError です.
+func errorKind(err error) ErrorKind {
+	var exprErr *ExprError
+	if errors.As(err, &exprErr) {
+		return exprErr.Kind
+	}
+	var ke *kindError
+	if errors.As(err, &ke) {
+		return ke.kind
+	}
+	return ParseError
+}
@@ -0,0 +1,737 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 計算式の文法
+//
+//   program = stmt { ";" stmt }
+//   stmt    = "let" ident "=" expr | expr
+//   expr    = term { ("+" | "-") term | "@" snap | term }
+//   term    = ("+" | "-") term | "(" expr ")" | ident | offset | epoch | date
+//   snap    = ("start" | "end") ("Y" | "M" | "W" | "D" | "h" | "m")
+//   offset  = number ("Y" | "M" | "W" | "D" | "B" | "h" | "m" | "s") | ISO 8601 の期間 | Go 形式の期間
+//   epoch   = ["@"] number
+//
+// 演算子を省略した term は加算になります. date は parseDate で解釈できる最長の文字列です.
+// 計算式はいったん構文木 (exprProgram) にしてから評価します. epoch は unix 秒です.
+
+var (
+	offsetRegexp = regexp.MustCompile(`^(\d+)([YMWDBhms])$`)
+	snapRegexp   = regexp.MustCompile(`^(start|end)([YMWDhm])$`)
+	identRegexp  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenPlus
+	tokenMinus
+	tokenAt
+	tokenLParen
+	tokenRParen
+	tokenSemicolon
+	tokenAssign
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+	end  int
+}
+
+// ExprError 計算式の誤り. 誤りの位置を示します.
+type ExprError struct {
+	Src  string
+	Pos  int
+	Msg  string
+	Kind ErrorKind
+}
+
+func (e *ExprError) Error() string {
+	column := utf8.RuneCountInString(e.Src[:e.Pos])
+	return fmt.Sprintf("'%s' is invalid format.\n  %s\n  %s^ %s", e.Src, e.Src, strings.Repeat(" ", column), e.Msg)
+}
+
+func lex(src string) []token {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+
+		kind := tokenWord
+		switch r {
+		case '+':
+			kind = tokenPlus
+		case '-':
+			kind = tokenMinus
+		case '@':
+			// @1526113800 は unix 秒の日時
+			if next, _ := utf8.DecodeRuneInString(src[i+size:]); unicode.IsDigit(next) == false {
+				kind = tokenAt
+			}
+		case '(':
+			kind = tokenLParen
+		case ')':
+			kind = tokenRParen
+		case ';':
+			kind = tokenSemicolon
+		case '=':
+			kind = tokenAssign
+		}
+		if kind != tokenWord {
+			tokens = append(tokens, token{kind: kind, text: string(r), pos: i, end: i + 1})
+			i++
+			continue
+		}
+
+		// 単語の途中の + や - は単語の一部 (2018-05-12, +09:00 など)
+		start := i
+		if r == '@' {
+			i += size
+		}
+		for i < len(src) {
+			r, size := utf8.DecodeRuneInString(src[i:])
+			if unicode.IsSpace(r) || strings.ContainsRune("@();=", r) {
+				break
+			}
+			i += size
+		}
+		tokens = append(tokens, token{kind: tokenWord, text: src[start:i], pos: start, end: i})
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src), end: len(src)})
+}
+
+// offsetStep 期間の 1 単位. unit が 0 のときは duration を加算します.
+type offsetStep struct {
+	unit     byte
+	n        int
+	duration Duration
+}
+
+func (s offsetStep) negate() offsetStep {
+	return offsetStep{unit: s.unit, n: -s.n, duration: s.duration.Negate()}
+}
+
+// apply dt に加算する. 月や年の加算で同じ日が存在しないときは adjust に従います.
+// --warn-dst のときは夏時間の切り替えをまたいだことを警告します.
+func (s offsetStep) apply(dt *Dt, adjust AdjustDay) (*Dt, error) {
+	result, err := s.add(dt, adjust)
+	if err == nil {
+		checkDSTCrossing(s.String(), dt.time, result.time)
+	}
+	return result, err
+}
+
+func (s offsetStep) String() string {
+	if s.unit == 0 {
+		return s.duration.ISOString()
+	}
+	return fmt.Sprintf("%+d%c", s.n, s.unit)
+}
+
+func (s offsetStep) add(dt *Dt, adjust AdjustDay) (*Dt, error) {
+	switch s.unit {
+	case 'Y':
+		return dt.AddYear(s.n, adjust)
+	case 'M':
+		return dt.AddMonth(s.n, adjust)
+	case 'W':
+		return dt.AddWeek(s.n)
+	case 'D':
+		return dt.AddDay(s.n)
+	case 'B':
+		return dt.AddBusinessDay(s.n)
+	case 'h':
+		return dt.AddHour(s.n), nil
+	case 'm':
+		return dt.AddMinute(s.n), nil
+	case 's':
+		return dt.AddSecond(s.n), nil
+	default:
+		return dt.AddDuration(s.duration, adjust)
+	}
+}
+
+// value 計算式の値. 日時か期間のどちらか.
+type value struct {
+	dt     *Dt
+	offset []offsetStep
+}
+
+// duration 期間を Duration にまとめる. 営業日を含むときはまとめられない.
+func (v value) duration() (Duration, bool) {
+	var d Duration
+	for _, s := range v.offset {
+		switch s.unit {
+		case 'Y':
+			d.Years += s.n
+		case 'M':
+			d.Months += s.n
+		case 'W':
+			d.Days += 7 * s.n
+		case 'D':
+			d.Days += s.n
+		case 'B':
+			return d, false
+		case 'h', 'm', 's':
+			d.Clock += clockUnits[s.unit] * time.Duration(s.n)
+		default:
+			d.Years += s.duration.Years
+			d.Months += s.duration.Months
+			d.Days += s.duration.Days
+			d.Clock += s.duration.Clock
+		}
+	}
+	return d, true
+}
+
+// evaluator 計算式を評価する. 変数と直前の結果を保持します.
+type evaluator struct {
+	vars        map[string]value
+	current     value
+	inputFormat string
+	adjust      AdjustDay
+	// zone タイムゾーンのない日時のタイムゾーン. nil のときは localLocation()
+	zone *time.Location
+}
+
+func newEvaluator(inputFormat string, adjust AdjustDay) *evaluator {
+	return &evaluator{vars: map[string]value{}, inputFormat: inputFormat, adjust: adjust}
+}
+
+func (e *evaluator) parseDate(s string) (*Dt, error) {
+	return parseDate(s, e.inputFormat, e.zone)
+}
+
+// first 最初の引数を評価する. 日時として解釈できないときは計算式として評価します.
+func (e *evaluator) first(src string) error {
+	dt, err := e.parseDate(src)
+	if err == nil {
+		e.current = value{dt: dt}
+		return nil
+	}
+	if _, ok := err.(dstError); ok {
+		// 日時としては正しいので計算式として解釈し直さない
+		return err
+	}
+	return e.run(src, false)
+}
+
+// rest 直前の結果に続けて引数を評価する. "+1D" や "@startM" などを受け付けます.
+func (e *evaluator) rest(src string) error {
+	return e.run(src, true)
+}
+
+// run src を構文木にしてから評価する. continued のときは最初の文を直前の結果の続きとして評価します.
+func (e *evaluator) run(src string, continued bool) error {
+	src, err := expandMacros(src)
+	if err != nil {
+		return err
+	}
+	prog, err := e.parse(src, continued)
+	if err != nil {
+		return err
+	}
+	log.Printf("expr: %s", prog)
+	v, err := prog.eval(e)
+	if err != nil {
+		return err
+	}
+	e.current = v
+	return nil
+}
+
+// parse src を構文木にする. 日時の解釈には入力フォーマットとタイムゾーンを使い,
+// 定義済みの変数は変数として扱います.
+func (e *evaluator) parse(src string, continued bool) (*exprProgram, error) {
+	vars := map[string]bool{}
+	for name := range e.vars {
+		vars[name] = true
+	}
+	p := &parser{src: src, tokens: lex(src), e: e, vars: vars, maxFields: maxDateFields(e.inputFormat)}
+	return p.program(continued)
+}
+
+// exprNode 計算式の構文木のノード
+type exprNode interface {
+	// pos エラーを示す位置
+	pos() int
+	String() string
+}
+
+type (
+	// dateNode 日時. src は parseDate で解釈できる文字列で, 評価するときに解釈します.
+	dateNode struct {
+		at  int
+		src string
+	}
+	// offsetNode 期間
+	offsetNode struct {
+		at   int
+		src  string
+		step offsetStep
+	}
+	// varNode 変数の参照
+	varNode struct {
+		at   int
+		name string
+	}
+	// prevNode 直前の結果
+	prevNode struct {
+		at int
+	}
+	// negNode 符号の反転
+	negNode struct {
+		at int
+		x  exprNode
+	}
+	// binaryNode 加算か減算. 演算子を省略したときは加算です.
+	binaryNode struct {
+		at   int
+		op   tokenKind
+		l, r exprNode
+	}
+	// snapNode @startM や @endD
+	snapNode struct {
+		at     int
+		unitAt int
+		x      exprNode
+		start  bool
+		unit   byte
+	}
+	// letNode 変数の定義
+	letNode struct {
+		at   int
+		name string
+		x    exprNode
+	}
+)
+
+func (n *dateNode) pos() int   { return n.at }
+func (n *offsetNode) pos() int { return n.at }
+func (n *varNode) pos() int    { return n.at }
+func (n *prevNode) pos() int   { return n.at }
+func (n *negNode) pos() int    { return n.at }
+func (n *binaryNode) pos() int { return n.at }
+func (n *snapNode) pos() int   { return n.at }
+func (n *letNode) pos() int    { return n.at }
+
+func (n *dateNode) String() string   { return "date(" + n.src + ")" }
+func (n *offsetNode) String() string { return n.src }
+func (n *varNode) String() string    { return n.name }
+func (n *prevNode) String() string   { return "_" }
+func (n *negNode) String() string    { return "-" + n.x.String() }
+func (n *binaryNode) String() string {
+	op := "+"
+	if n.op == tokenMinus {
+		op = "-"
+	}
+	return "(" + n.l.String() + " " + op + " " + n.r.String() + ")"
+}
+func (n *snapNode) String() string {
+	kind := "end"
+	if n.start {
+		kind = "start"
+	}
+	return n.x.String() + " @" + kind + string(n.unit)
+}
+func (n *letNode) String() string { return "let " + n.name + " = " + n.x.String() }
+
+// exprProgram 計算式の構文木. 文を順に評価して最後の値が結果です.
+type exprProgram struct {
+	src   string
+	stmts []exprNode
+}
+
+func (p *exprProgram) String() string {
+	s := make([]string, len(p.stmts))
+	for i, n := range p.stmts {
+		s[i] = n.String()
+	}
+	return strings.Join(s, "; ")
+}
+
+func (p *exprProgram) evalErrorf(pos int, format string, a ...interface{}) error {
+	return &ExprError{Src: p.src, Pos: pos, Msg: fmt.Sprintf(format, a...), Kind: EvalError}
+}
+
+// eval 文を順に評価する. 変数の定義は e に残ります.
+func (p *exprProgram) eval(e *evaluator) (value, error) {
+	var v value
+	for _, n := range p.stmts {
+		var err error
+		if v, err = p.evalNode(e, n); err != nil {
+			return v, err
+		}
+	}
+	return v, nil
+}
+
+func (p *exprProgram) evalNode(e *evaluator, n exprNode) (value, error) {
+	switch n := n.(type) {
+	case *dateNode:
+		dt, err := e.parseDate(n.src)
+		if err != nil {
+			// 構文木にしたときに解釈できているので, 夏時間の切り替えのエラーです
+			return value{}, err
+		}
+		log.Printf("date: %s, time: %v", n.src, dt.time)
+		return value{dt: dt}, nil
+	case *offsetNode:
+		return value{offset: []offsetStep{n.step}}, nil
+	case *varNode:
+		v, ok := e.vars[n.name]
+		if ok == false {
+			return value{}, p.evalErrorf(n.at, "unknown variable '%s'", n.name)
+		}
+		return v, nil
+	case *prevNode:
+		return e.current, nil
+	case *negNode:
+		v, err := p.evalNode(e, n.x)
+		if err != nil {
+			return v, err
+		}
+		if v.dt != nil {
+			return v, p.evalErrorf(n.at, "cannot negate a date")
+		}
+		return value{offset: negateOffset(v.offset)}, nil
+	case *binaryNode:
+		l, err := p.evalNode(e, n.l)
+		if err != nil {
+			return l, err
+		}
+		r, err := p.evalNode(e, n.r)
+		if err != nil {
+			return r, err
+		}
+		return p.binary(e, n, l, r)
+	case *snapNode:
+		v, err := p.evalNode(e, n.x)
+		if err != nil {
+			return v, err
+		}
+		if v.dt == nil {
+			return v, p.evalErrorf(n.at, "cannot snap a duration")
+		}
+		var dt *Dt
+		if n.start {
+			dt, err = v.dt.StartOf(n.unit)
+		} else {
+			dt, err = v.dt.EndOf(n.unit)
+		}
+		if err != nil {
+			return v, p.evalErrorf(n.unitAt, "%v", err)
+		}
+		return value{dt: dt}, nil
+	case *letNode:
+		v, err := p.evalNode(e, n.x)
+		if err != nil {
+			return v, err
+		}
+		log.Printf("let %s = %+v", n.name, v)
+		e.vars[n.name] = v
+		return v, nil
+	default:
+		return value{}, p.evalErrorf(n.pos(), "unknown node")
+	}
+}
+
+func (p *exprProgram) binary(e *evaluator, n *binaryNode, l, r value) (value, error) {
+	if n.op == tokenMinus {
+		if l.dt != nil && r.dt != nil {
+			d := durationBetween(r.dt.time, l.dt.time)
+			return value{offset: []offsetStep{{duration: d}}}, nil
+		}
+		if r.dt != nil {
+			return l, p.evalErrorf(n.at, "cannot subtract a date from a duration")
+		}
+		r = value{offset: negateOffset(r.offset)}
+	}
+
+	switch {
+	case l.dt != nil && r.dt != nil:
+		return l, p.evalErrorf(n.at, "cannot add two dates")
+	case l.dt != nil, r.dt != nil:
+		dt, offset := l.dt, r.offset
+		if dt == nil {
+			dt, offset = r.dt, l.offset
+		}
+		result, err := applyOffset(dt, offset, e.adjust)
+		if err != nil {
+			return l, p.evalErrorf(n.at, "%v", err)
+		}
+		return value{dt: result}, nil
+	default:
+		offset := append(append([]offsetStep{}, l.offset...), r.offset...)
+		return value{offset: offset}, nil
+	}
+}
+
+// parser トークンを構文木にする. 評価はしません.
+type parser struct {
+	src    string
+	tokens []token
+	i      int
+	e      *evaluator
+	// vars 変数として扱う名前. let で定義すると増えます.
+	vars map[string]bool
+	// maxFields 日時として試す最大の単語数
+	maxFields int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) errorf(pos int, format string, a ...interface{}) error {
+	return &ExprError{Src: p.src, Pos: pos, Msg: fmt.Sprintf(format, a...), Kind: ParseError}
+}
+
+// program 文を順に読む. continued のときは最初の文を直前の結果の続きとして読みます.
+func (p *parser) program(continued bool) (*exprProgram, error) {
+	prog := &exprProgram{src: p.src}
+	for {
+		n, err := p.statement(continued)
+		if err != nil {
+			return nil, err
+		}
+		prog.stmts = append(prog.stmts, n)
+		continued = false
+
+		t := p.next()
+		switch t.kind {
+		case tokenEOF:
+			return prog, nil
+		case tokenSemicolon:
+			if p.peek().kind == tokenEOF {
+				return prog, nil
+			}
+		default:
+			return nil, p.errorf(t.pos, "unexpected '%s'", t.text)
+		}
+	}
+}
+
+func (p *parser) statement(continued bool) (exprNode, error) {
+	t := p.peek()
+	if continued {
+		return p.expr(&prevNode{at: t.pos})
+	}
+	if t.kind != tokenWord || t.text != "let" {
+		return p.expr(nil)
+	}
+
+	p.next()
+	name := p.next()
+	if name.kind != tokenWord || !identRegexp.MatchString(name.text) {
+		return nil, p.errorf(name.pos, "variable name expected")
+	}
+	if assign := p.next(); assign.kind != tokenAssign {
+		return nil, p.errorf(assign.pos, "'=' expected")
+	}
+	x, err := p.expr(nil)
+	if err != nil {
+		return nil, err
+	}
+	p.vars[name.text] = true
+	return &letNode{at: t.pos, name: name.text, x: x}, nil
+}
+
+func (p *parser) expr(left exprNode) (exprNode, error) {
+	n := left
+	if n == nil {
+		var err error
+		if n, err = p.term(); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		t := p.peek()
+		switch t.kind {
+		case tokenPlus, tokenMinus:
+			p.next()
+			r, err := p.term()
+			if err != nil {
+				return nil, err
+			}
+			n = &binaryNode{at: t.pos, op: t.kind, l: n, r: r}
+		case tokenWord, tokenLParen:
+			// 演算子を省略したときは加算
+			r, err := p.term()
+			if err != nil {
+				return nil, err
+			}
+			n = &binaryNode{at: t.pos, op: tokenPlus, l: n, r: r}
+		case tokenAt:
+			p.next()
+			u := p.next()
+			m := snapRegexp.FindStringSubmatch(u.text)
+			if u.kind != tokenWord || m == nil {
+				return nil, p.errorf(u.pos, "snap expected (e.g. startM, endD)")
+			}
+			n = &snapNode{at: t.pos, unitAt: u.pos, x: n, start: m[1] == "start", unit: m[2][0]}
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) term() (exprNode, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenPlus:
+		p.next()
+		return p.term()
+	case tokenMinus:
+		p.next()
+		x, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		return &negNode{at: t.pos, x: x}, nil
+	case tokenLParen:
+		p.next()
+		x, err := p.expr(nil)
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokenRParen {
+			return nil, p.errorf(r.pos, "')' expected")
+		}
+		return x, nil
+	case tokenWord:
+		return p.word()
+	case tokenEOF:
+		return nil, p.errorf(t.pos, "unexpected end of expression")
+	default:
+		return nil, p.errorf(t.pos, "unexpected '%s'", t.text)
+	}
+}
+
+func (p *parser) word() (exprNode, error) {
+	t := p.peek()
+	if p.vars[t.text] {
+		p.next()
+		return &varNode{at: t.pos, name: t.text}, nil
+	}
+	if step, ok := parseOffset(t.text); ok {
+		p.next()
+		return &offsetNode{at: t.pos, src: t.text, step: step}, nil
+	}
+
+	// 空白や演算子を含む日時もあるので, maxFields 語までの日時として解釈できる最長の文字列を探す
+	last := p.i
+	fields := 1
+	for last+1 < len(p.tokens) {
+		next := p.tokens[last+1]
+		if next.kind == tokenEOF || next.kind == tokenSemicolon || next.kind == tokenLParen ||
+			next.kind == tokenRParen || next.kind == tokenAt || next.kind == tokenAssign {
+			break
+		}
+		if next.pos > p.tokens[last].end {
+			// 空白をはさむと単語が増える
+			if fields++; fields > p.maxFields {
+				break
+			}
+		}
+		last++
+	}
+	for j := last; j >= p.i; j-- {
+		s := p.src[t.pos:p.tokens[j].end]
+		_, err := p.e.parseDate(s)
+		if _, ok := err.(dstError); err == nil || ok {
+			p.i = j + 1
+			return &dateNode{at: t.pos, src: s}, nil
+		}
+	}
+	return nil, p.errorf(t.pos, "unknown term '%s'", t.text)
+}
+
+// maxDateFields 日時として試す最大の単語数. 入力フォーマットと既知のフォーマットの空白で区切った単語数の最大です.
+func maxDateFields(inputFormat string) int {
+	n := 1
+	layouts := []string{inputFormat}
+	for _, f := range formats {
+		layouts = append(layouts, f)
+	}
+	for _, l := range layouts {
+		if k := len(strings.Fields(l)); k > n {
+			n = k
+		}
+	}
+	return n
+}
+
+func parseOffset(s string) (offsetStep, bool) {
+	if m := offsetRegexp.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return offsetStep{}, false
+		}
+		return offsetStep{unit: m[2][0], n: n}, true
+	}
+	if d, ok := parseDuration(s); ok {
+		return offsetStep{duration: d}, true
+	}
+	return offsetStep{}, false
+}
+
+func negateOffset(offset []offsetStep) []offsetStep {
+	result := make([]offsetStep, len(offset))
+	for i, s := range offset {
+		result[i] = s.negate()
+	}
+	return result
+}
+
+// scaleOffset 期間を k 倍する.
+func scaleOffset(offset []offsetStep, k int) []offsetStep {
+	result := make([]offsetStep, len(offset))
+	for i, s := range offset {
+		d := s.duration
+		result[i] = offsetStep{
+			unit:     s.unit,
+			n:        s.n * k,
+			duration: Duration{Years: d.Years * k, Months: d.Months * k, Days: d.Days * k, Clock: d.Clock * time.Duration(k)},
+		}
+	}
+	return result
+}
+
+func applyOffset(dt *Dt, offset []offsetStep, adjust AdjustDay) (*Dt, error) {
+	for _, s := range offset {
+		next, err := s.apply(dt, adjust)
+		if err != nil {
+			return dt, err
+		}
+		log.Printf("offset: %+v, time: %v -> %v", s, dt.time, next.time)
+		dt = next
+	}
+	return dt, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tokens := lex("let d = 2018-05-12; (d -1D) @startM")
+	expect := []tokenKind{
+		tokenWord, tokenWord, tokenAssign, tokenWord, tokenSemicolon,
+		tokenLParen, tokenWord, tokenMinus, tokenWord, tokenRParen, tokenAt, tokenWord, tokenEOF,
+	}
+
+	if len(tokens) != len(expect) {
+		t.Fatalf("lex() = %v, want %d tokens", tokens, len(expect))
+	}
+	for i, k := range expect {
+		if tokens[i].kind != k {
+			t.Errorf("lex()[%d] = %v, want kind %d", i, tokens[i], k)
+		}
+	}
+	if tokens[3].text != "2018-05-12" || tokens[3].pos != 8 {
+		t.Errorf("lex()[3] = %v, want 2018-05-12 at 8", tokens[3])
+	}
+}
+
+func TestEvaluator_parse(t *testing.T) {
+	params := []struct {
+		src       string
+		continued bool
+		expect    string
+	}{
+		{src: "2024-01-31 + 1M - 1D", expect: "((date(2024-01-31) + 1M) - 1D)"},
+		{src: "let d = 2018/05/12 17:30:00; (d -1D) @startM", expect: "let d = date(2018/05/12 17:30:00); (d - 1D) @startM"},
+		{src: "-1D 2W", expect: "(-1D + 2W)"},
+		{src: "@1526113800 + PT1H", expect: "(date(@1526113800) + PT1H)"},
+		{src: "+1D @endM", continued: true, expect: "(_ + 1D) @endM"},
+	}
+	for _, p := range params {
+		e := newEvaluator("", AdjustToEndOfMonth)
+		prog, err := e.parse(p.src, p.continued)
+		if err != nil {
+			t.Errorf("parse(%q) = %v", p.src, err)
+			continue
+		}
+		if actual := prog.String(); actual != p.expect {
+			t.Errorf("parse(%q) = %s; want %s", p.src, actual, p.expect)
+		}
+	}
+}
+
+func TestRun_expr(t *testing.T) {
+	nowInterface = &MyTime{}
+	params := []struct {
+		args   []string
+		expect string
+	}{
+		{args: []string{AppName, "2024-01-31 + 1M - 1D"}, expect: "2024-03-01"},
+		{args: []string{AppName, "-a", "2024-01-31 + 1M - 1D"}, expect: "2024-02-28"},
+		{args: []string{AppName, "now @startM - 1B"}, expect: "2018/04/30 00:00:00"},
+		{args: []string{AppName, "now @endW"}, expect: "2018/05/13 23:59:59"},
+		{args: []string{AppName, "let d = 2024-03-01; d - 1D"}, expect: "2024-02-29"},
+		{args: []string{AppName, "let x = 1D; 2024-01-01 + x + x"}, expect: "2024-01-03"},
+		{args: []string{AppName, "(2024-03-01 + 1h) @startD + 2B"}, expect: "2024-03-05"},
+		{args: []string{AppName, "2018/05/12 17:30:00 +1Y -2M 3D"}, expect: "2019/03/15 17:30:00"},
+		{args: []string{AppName, "2018/05/12 17:30:00 + 2W"}, expect: "2018/05/26 17:30:00"},
+		{args: []string{AppName, "2024-03-01 - 2024-01-01"}, expect: "P2M"},
+		{args: []string{AppName, "-o", "diff", "2024-01-02 - 2024-01-01"}, expect: "24h0m0s"},
+		{args: []string{AppName, "1M + 1D"}, expect: "P1M1D"},
+		{args: []string{AppName, "12 May 18 17:30 +0900 + 1D"}, expect: "13 May 18 17:30 +0900"},
+		{args: []string{AppName, "2024-01-31", "@endM", "- 1D"}, expect: "2024-01-30"},
+		{args: []string{AppName, "@1526113800 + 1D"}, expect: "1526200200"},
+		{args: []string{AppName, "1526113800 + 1D"}, expect: "1526200200"},
+		{args: []string{AppName, "-i", "unix", "-o", "def", "1526113800", "+1D"}, expect: "2018/05/13 17:30:00"},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+
+		status := clo.Run(p.args)
+		if status != ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream)
+		}
+
+		actual := outStream.String()
+		if strings.Contains(actual, p.expect) == false {
+			t.Errorf("Run(%s): Output = %v; want %v", p.args, actual, p.expect)
+		}
+	}
+}
+
+func TestRun_exprError(t *testing.T) {
+	nowInterface = &MyTime{}
+	params := []struct {
+		args   []string
+		expect string
+	}{
+		{args: []string{AppName, "2024-01-31 + 1x"}, expect: "  2024-01-31 + 1x\n               ^ unknown term '1x'"},
+		{args: []string{AppName, "now + (1D"}, expect: "         ^ ')' expected"},
+		{args: []string{AppName, "now + now"}, expect: "      ^ cannot add two dates"},
+		{args: []string{AppName, "1D @startM"}, expect: "   ^ cannot snap a duration"},
+		{args: []string{AppName, "now @startX"}, expect: "snap expected"},
+		{args: []string{AppName, "let = 1D"}, expect: "variable name expected"},
+		{args: []string{AppName, "1B"}, expect: "business days cannot be printed as a duration."},
+		{args: []string{AppName, "--tz", "America/New_York", "--on-dst", "error", "2024-03-09 02:30:00 + 1D; 2024-03-10 02:30:00"}, expect: "'2024-03-10 02:30:00' does not exist in America/New_York."},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+
+		status := clo.Run(p.args)
+		if status != ExitCodeError {
+			t.Errorf("Run(%s): ExitStatus = %d; want %d", p.args, status, ExitCodeError)
+		}
+
+		actual := errStream.String()
+		if strings.Contains(actual, p.expect) == false {
+			t.Errorf("Run(%s): Output = %q; want %q", p.args, actual, p.expect)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+func filterCommand() cli.Command {
+	return cli.Command{
+		Name:      "filter",
+		Usage:     "標準入力の行を日時の範囲で絞り込みます",
+		UsageText: AppName + " filter [options]",
+		Description: `各行から日時を取り出し, --from 以上 --to 未満の行だけを出力します.
+   日時は -f のフィールド, -p の正規表現, -i のレイアウトのいずれかで取り出し, 引数と同じ方法で解釈します.
+   日時のない行は直前の行と同じ扱いになります (スタックトレースなどの複数行のログのため).`,
+		HideHelp: true,
+		Flags: commandFlags(append(extractFlags(),
+			cli.StringFlag{
+				Name:  "from",
+				Usage: "範囲の始まりを計算式で指定します. この日時を含みます",
+			},
+			cli.StringFlag{
+				Name:  "to",
+				Usage: "範囲の終わりを計算式で指定します. この日時を含みません",
+			},
+			cli.BoolFlag{
+				Name:  "sorted",
+				Usage: "入力が日時の順に並んでいるとみなし, --to を過ぎたら読むのをやめます",
+			},
+		)...),
+		Action: commandAction(filter),
+	}
+}
+
+// lineFilter 日時の範囲で行を絞り込む
+type lineFilter struct {
+	extract func(line string) (string, bool)
+	from    *time.Time
+	to      *time.Time
+	sorted  bool
+}
+
+func filter(c *cli.Context) error {
+	f := &lineFilter{sorted: c.Bool("sorted")}
+
+	var err error
+	if f.from, err = filterBound(c, "from"); err != nil {
+		return err
+	}
+	if f.to, err = filterBound(c, "to"); err != nil {
+		return err
+	}
+	if f.extract, err = timestampExtractor(c); err != nil {
+		return err
+	}
+
+	return f.run(clo.inStream, clo.outStream)
+}
+
+// filterBound --from や --to の計算式を評価する. 指定がないときは nil を返します.
+func filterBound(c *cli.Context, name string) (*time.Time, error) {
+	src := c.String(name)
+	if src == "" {
+		return nil, nil
+	}
+	v, err := evalArgs(c, []string{src})
+	if err != nil {
+		return nil, err
+	}
+	if v.dt == nil {
+		return nil, evalError(fmt.Errorf("--%s '%s' is not a date.", name, src))
+	}
+	log.Printf("filter %s: %v", name, v.dt.time)
+	return &v.dt.time, nil
+}
+
+// extractFlags 行から日時を取り出す方法を指定するオプション
+func extractFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "field, f",
+			Usage: "日時のフィールドの番号を 1 から数えて指定します. 2-3 のように範囲も指定できます",
+		},
+		cli.StringFlag{
+			Name:  "delimiter, F",
+			Usage: "フィールドの区切り文字を指定します. 省略したときは空白で区切ります",
+		},
+		cli.StringFlag{
+			Name:  "pattern, p",
+			Usage: "日時を取り出す正規表現を指定します. グループがあるときは最初のグループを使います",
+		},
+	}
+}
+
+// timestampExtractor 行から日時の部分を取り出す関数を作る.
+func timestampExtractor(c *cli.Context) (func(string) (string, bool), error) {
+	switch {
+	case c.String("pattern") != "":
+		re, err := regexp.Compile(c.String("pattern"))
+		if err != nil {
+			return nil, err
+		}
+		return regexpExtractor(re), nil
+	case c.String("field") != "":
+		return fieldExtractor(c.String("field"), c.String("delimiter"))
+	case c.String("i") != "":
+		re, err := layoutRegexp(c.String("i"))
+		if err != nil {
+			return nil, err
+		}
+		return regexpExtractor(re), nil
+	default:
+		return nil, fmt.Errorf("%s needs -f, -p or -i.", c.Command.Name)
+	}
+}
+
+func regexpExtractor(re *regexp.Regexp) func(string) (string, bool) {
+	return func(line string) (string, bool) {
+		m := re.FindStringSubmatch(line)
+		switch {
+		case m == nil:
+			return "", false
+		case len(m) > 1:
+			return m[1], true
+		default:
+			return m[0], true
+		}
+	}
+}
+
+var fieldRangeRegexp = regexp.MustCompile(`^(\d+)(?:-(\d+))?$`)
+
+func fieldExtractor(field, delimiter string) (func(string) (string, bool), error) {
+	m := fieldRangeRegexp.FindStringSubmatch(field)
+	if m == nil {
+		return nil, fmt.Errorf("'%s' is invalid field.", field)
+	}
+	first, _ := strconv.Atoi(m[1])
+	last := first
+	if m[2] != "" {
+		last, _ = strconv.Atoi(m[2])
+	}
+	if first < 1 || last < first {
+		return nil, fmt.Errorf("'%s' is invalid field.", field)
+	}
+
+	return func(line string) (string, bool) {
+		var fields []string
+		sep := delimiter
+		if delimiter == "" {
+			fields = strings.Fields(line)
+			sep = " "
+		} else {
+			fields = strings.Split(line, delimiter)
+		}
+		if len(fields) < last {
+			return "", false
+		}
+		return strings.Join(fields[first-1:last], sep), true
+	}, nil
+}
+
+// run in の行のうち範囲内のものを out に書く.
+func (f *lineFilter) run(in io.Reader, out io.Writer) error {
+	reader := bufio.NewReaderSize(in, 64*1024)
+	writer := bufio.NewWriterSize(out, 64*1024)
+	defer writer.Flush()
+
+	// 日時のない行は直前の行に続くものとして扱う
+	inRange := false
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			if t, ok := f.timestamp(strings.TrimRight(line, "\r\n")); ok {
+				if f.sorted && f.to != nil && t.Before(*f.to) == false {
+					return nil
+				}
+				inRange = f.contains(t)
+			}
+			if inRange {
+				if _, err := writer.WriteString(line); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (f *lineFilter) timestamp(line string) (time.Time, bool) {
+	return lineTimestamp(f.extract, line)
+}
+
+// lineTimestamp extract で取り出した行の日時を最初の引数と同じ方法で解釈する.
+func lineTimestamp(extract func(string) (string, bool), line string) (time.Time, bool) {
+	s, ok := extract(line)
+	if ok == false {
+		return time.Time{}, false
+	}
+	dt, err := processFirst(s)
+	if err != nil {
+		log.Printf("'%s' is not a date", s)
+		return time.Time{}, false
+	}
+	return dt.time, true
+}
+
+// contains from 以上 to 未満のときは true.
+func (f *lineFilter) contains(t time.Time) bool {
+	if f.from != nil && t.Before(*f.from) {
+		return false
+	}
+	return f.to == nil || t.Before(*f.to)
+}
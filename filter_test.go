@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_filter(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	nowInterface = &MyTime{}
+	input := strings.Join([]string{
+		"2018-05-11 09:59:59 a",
+		"2018-05-11 10:00:00 b",
+		"  at trace",
+		"2018-05-11 10:14:59 c",
+		"2018-05-11 10:15:00 d",
+		"  at trace",
+		"2018-05-11 10:05:00 e",
+		"",
+	}, "\n")
+
+	params := []struct {
+		args   []string
+		expect []string
+	}{
+		{
+			// ホストのタイムゾーンによらないように現在時刻とタイムゾーンを固定する
+			args:   []string{"--tz", "Asia/Tokyo", "--now", "2018-05-12T17:30:00+09:00", "-f", "1-2", "--from", "now -1D @startD +10h", "--to", "now -1D @startD +10h15m"},
+			expect: []string{"2018-05-11 10:00:00 b", "  at trace", "2018-05-11 10:14:59 c", "2018-05-11 10:05:00 e"},
+		},
+		{
+			args:   []string{"-i", "2006-01-02 15:04:05", "--sorted", "--from", "2018-05-11 10:00:00", "--to", "2018-05-11 10:15:00"},
+			expect: []string{"2018-05-11 10:00:00 b", "  at trace", "2018-05-11 10:14:59 c"},
+		},
+		{
+			args:   []string{"-p", `^(\S+ \S+) [de]`, "--from", "2018-05-11 10:10:00"},
+			expect: []string{"2018-05-11 10:15:00 d", "  at trace"},
+		},
+		{
+			args:   []string{"-F", ":", "-f", "1-2", "--to", "2018-05-11 10:00:00"},
+			expect: []string{"2018-05-11 09:59:59 a"},
+		},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := clo.Run(append([]string{AppName, "filter"}, p.args...))
+		if status != ExitCodeOK {
+			t.Fatalf("Run(%v): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream)
+		}
+		expect := strings.Join(p.expect, "\n") + "\n"
+		if outStream.String() != expect {
+			t.Errorf("Run(%v): Output = %q; want %q", p.args, outStream, expect)
+		}
+	}
+}
+
+func TestRun_filterError(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	params := [][]string{
+		{"--from", "2018-05-11"},
+		{"-f", "0"},
+		{"-f", "3-2"},
+		{"-f", "1", "--from", "1D"},
+		{"-p", "(", "--from", "2018-05-11"},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{inStream: strings.NewReader(""), outStream: outStream, errStream: errStream}
+
+		status := clo.Run(append([]string{AppName, "filter"}, p...))
+		if status != ExitCodeError {
+			t.Errorf("Run(%v): ExitStatus = %d; want %d", p, status, ExitCodeError)
+		}
+	}
+}
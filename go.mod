@@ -4,6 +4,7 @@ go 1.18
 
 require (
 	github.com/mitchellh/go-homedir v1.1.0
+	github.com/pelletier/go-toml v1.9.5
 	github.com/urfave/cli v1.22.8
 )
 
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const holidayFileName = "holidays"
+
+// holidays 祝日の名前. キーは "2006-01-02" 形式の日付
+var holidays = map[string]string{}
+
+// loadHolidays 祝日のファイルを読む. 複数のファイルを指定したときはすべて読みます.
+// path が空のときは設定ディレクトリの holidays を読みます.
+// ファイルは 1 行にひとつ "2024-01-01 元日" のように日付と名前を書きます. # から行末まではコメントです.
+func loadHolidays(paths ...string) error {
+	holidays = map[string]string{}
+	for _, path := range paths {
+		if err := readHolidays(path, holidays); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readHolidays 祝日のファイルを読んで days に追加する.
+func readHolidays(path string, days map[string]string) error {
+	optional := path == ""
+	if optional {
+		dir, err := configDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(dir, holidayFileName)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if optional {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		t, err := time.Parse("2006-01-02", fields[0])
+		if err != nil {
+			return fmt.Errorf("%s:%d: '%s' is invalid date.", path, n, fields[0])
+		}
+		days[t.Format("2006-01-02")] = strings.Join(fields[1:], " ")
+	}
+	log.Printf("holidays: %d days from %s", len(days), path)
+	return scanner.Err()
+}
+
+// holidayName t が祝日のときはその名前を返す.
+func holidayName(t time.Time) (string, bool) {
+	name, ok := holidays[t.Format("2006-01-02")]
+	return name, ok
+}
+
+// loadZoneHolidays 設定ファイルの [holidays] でタイムゾーン name に指定した祝日のファイルを読む.
+func loadZoneHolidays(name string) (map[string]string, error) {
+	days := map[string]string{}
+	for _, path := range config.zoneHolidays[name] {
+		if err := readHolidays(path, days); err != nil {
+			return nil, err
+		}
+	}
+	return days, nil
+}
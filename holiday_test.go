@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadHolidays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "holidays")
+	content := "# 2024\n2024-01-01 元日\n\n2024-02-11 建国記念の日 # 日曜日\n2024-02-12\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer loadHolidays("/dev/null")
+
+	if err := loadHolidays(path); err != nil {
+		t.Fatalf("loadHolidays() = %v", err)
+	}
+	params := []struct {
+		date   string
+		expect string
+		ok     bool
+	}{
+		{date: "2024-01-01", expect: "元日", ok: true},
+		{date: "2024-02-11", expect: "建国記念の日", ok: true},
+		{date: "2024-02-12", expect: "", ok: true},
+		{date: "2024-02-13", ok: false},
+	}
+	for _, p := range params {
+		d, _ := time.Parse("2006-01-02", p.date)
+		actual, ok := holidayName(d)
+		if actual != p.expect || ok != p.ok {
+			t.Errorf("holidayName(%s) = %s, %v; want %s, %v", p.date, actual, ok, p.expect, p.ok)
+		}
+	}
+
+	// 祝日は営業日に数えない
+	d, _ := time.Parse("2006-01-02", "2024-02-09")
+	result, _ := (&Dt{time: d}).AddBusinessDay(1)
+	actual := result.time.Format("2006-01-02")
+	if actual != "2024-02-13" {
+		t.Errorf("AddBusinessDay(1) = %s; want 2024-02-13", actual)
+	}
+}
+
+func TestLoadHolidays_invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "holidays")
+	if err := ioutil.WriteFile(path, []byte("2024-01-01 元日\n2024/02/11 建国記念の日\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer loadHolidays("/dev/null")
+
+	err := loadHolidays(path)
+	expect := path + ":2: '2024/02/11' is invalid date."
+	if err == nil || err.Error() != expect {
+		t.Errorf("loadHolidays() = %v; want %s", err, expect)
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/urfave/cli"
+)
+
+const (
+	// icsFormat 日時を iCalendar (RFC 5545) の予定として出力する
+	icsFormat = "ics"
+
+	icsProdID = "-//ebc-2in2crc//" + AppName + "//EN"
+	// icsLineLength 折り返すまでの 1 行のオクテット数
+	icsLineLength = 75
+
+	icsDateLayout     = "20060102"
+	icsDateTimeLayout = "20060102T150405"
+)
+
+func icsCommand() cli.Command {
+	return cli.Command{
+		Name:      "ics",
+		Usage:     "標準入力の日時を iCalendar の予定にします",
+		UsageText: AppName + " ics [options] [expr [expr ...]]",
+		Description: `標準入力の各行を計算式として評価し, 引数の計算式を続けて評価した日時を
+   1 行に 1 つの予定にしたカレンダーを出力します. 空行は読み飛ばします.
+   行をタブで区切ったときは 2 つ目を予定の件名にします. 件名のない行は --summary の件名です.`,
+		HideHelp: true,
+		Flags:    commandFlags(),
+		Action:   commandAction(icsBatch),
+	}
+}
+
+// icsEvent カレンダーのひとつの予定
+type icsEvent struct {
+	dt      *Dt
+	summary string
+	// source 予定を計算した計算式. 結果の日時が変わっても同じ UID にするために使います.
+	source string
+	// occurrence cron や rrule の繰り返しのその回の日時. UID に含めるので, 計算の元の日時が変わっても同じ回は同じ UID です.
+	occurrence time.Time
+}
+
+// icsOptions --event-duration と --all-day
+type icsOptions struct {
+	duration time.Duration
+	allDay   bool
+}
+
+func icsBatch(c *cli.Context) error {
+	var events []icsEvent
+	scanner := bufio.NewScanner(clo.inStream)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		src, summary := line, c.String("summary")
+		if i := strings.Index(line, "\t"); i >= 0 {
+			src, summary = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		}
+		v, err := evalDate(newEvaluator(c.String("i"), adjustPolicy(c)), src, c.Args())
+		if err == nil && v.dt == nil {
+			err = evalError(fmt.Errorf("'%s' is not a date.", src))
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+		source := strings.Join(append([]string{src}, c.Args()...), " ")
+		events = append(events, icsEvent{dt: v.dt, summary: summary, source: source})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return outputCalendar(events)
+}
+
+// outputCalendar 予定をカレンダーにして出力する.
+func outputCalendar(events []icsEvent) error {
+	opt, err := parseICSOptions(cliContext)
+	if err != nil {
+		return err
+	}
+	if seed := cliContext.String("uid"); seed != "" {
+		for i := range events {
+			events[i].source = seed
+		}
+	}
+	s := icsCalendar(events, opt, now())
+	_, err = fmt.Fprint(clo.outStream, s)
+	return err
+}
+
+// parseICSOptions --event-duration と --all-day を読む. 終日の予定の長さは日数で, デフォルトは 1 日です.
+func parseICSOptions(c *cli.Context) (icsOptions, error) {
+	opt := icsOptions{allDay: c.Bool("all-day")}
+	if s := c.String("event-duration"); s != "" {
+		d, err := parsePositiveDuration(s)
+		if err != nil {
+			return icsOptions{}, err
+		}
+		if opt.allDay && d%(24*time.Hour) != 0 {
+			return icsOptions{}, fmt.Errorf("'%s' is invalid duration for --all-day.", s)
+		}
+		opt.duration = d
+	}
+	if opt.allDay && opt.duration == 0 {
+		opt.duration = 24 * time.Hour
+	}
+	return opt, nil
+}
+
+// icsCalendar 予定を VCALENDAR にする. 行の区切りは CRLF で, 75 オクテットを超える行は折り返します.
+// タイムゾーンの名前がわかるときは TZID をつけて VTIMEZONE を含め, わからないときは UTC で出力します.
+// UID は計算式か --uid と件名から作るので, 計算した日時が変わっても同じ予定を取り込み直すと重複せずに更新されます.
+func icsCalendar(events []icsEvent, opt icsOptions, stamp time.Time) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:" + icsProdID,
+		"CALSCALE:GREGORIAN",
+	}
+
+	type zoneRange struct {
+		loc      *time.Location
+		from, to time.Time
+	}
+	var tzids []string
+	ranges := map[string]*zoneRange{}
+	var vevents []string
+	uids := map[string]int{}
+
+	for _, e := range events {
+		loc := e.dt.time.Location()
+		if zone != nil {
+			loc = zone
+		}
+		start := e.dt.time.In(loc)
+		end := start.Add(opt.duration)
+
+		var dtstart, dtend string
+		switch tzid := icsZoneName(start); {
+		case opt.allDay:
+			days := int(opt.duration / (24 * time.Hour))
+			dtstart = ";VALUE=DATE:" + start.Format(icsDateLayout)
+			dtend = ";VALUE=DATE:" + start.AddDate(0, 0, days).Format(icsDateLayout)
+		case tzid != "":
+			dtstart = ";TZID=" + tzid + ":" + start.Format(icsDateTimeLayout)
+			dtend = ";TZID=" + tzid + ":" + end.Format(icsDateTimeLayout)
+			r, ok := ranges[tzid]
+			if ok == false {
+				r = &zoneRange{loc: loc, from: start, to: end}
+				ranges[tzid] = r
+				tzids = append(tzids, tzid)
+			}
+			if start.Before(r.from) {
+				r.from = start
+			}
+			if end.After(r.to) {
+				r.to = end
+			}
+		default:
+			dtstart = ":" + start.UTC().Format(icsDateTimeLayout) + "Z"
+			dtend = ":" + end.UTC().Format(icsDateTimeLayout) + "Z"
+		}
+
+		// 同じ計算式と件名の予定が複数あるときは 2 つ目から番号をつける
+		source := e.source
+		if e.occurrence.IsZero() == false {
+			source += "\n" + e.occurrence.UTC().Format(icsDateTimeLayout) + "Z"
+		}
+		uid := icsUID(source, e.summary)
+		uids[uid]++
+		if n := uids[uid]; n > 1 {
+			uid = fmt.Sprintf("%s-%d", uid, n)
+		}
+
+		vevents = append(vevents,
+			"BEGIN:VEVENT",
+			"UID:"+uid+"@"+AppName,
+			"DTSTAMP:"+stamp.UTC().Format(icsDateTimeLayout)+"Z",
+			"DTSTART"+dtstart,
+		)
+		if opt.duration > 0 {
+			vevents = append(vevents, "DTEND"+dtend)
+		}
+		if e.summary != "" {
+			vevents = append(vevents, "SUMMARY:"+escapeICSText(e.summary))
+		}
+		vevents = append(vevents, "END:VEVENT")
+	}
+
+	for _, tzid := range tzids {
+		r := ranges[tzid]
+		lines = append(lines, vtimezone(tzid, r.loc, r.from, r.to)...)
+	}
+	lines = append(lines, vevents...)
+	lines = append(lines, "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(foldICSLine(line))
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+// icsUID 計算式と件名から UID を作る.
+func icsUID(source, summary string) string {
+	sum := sha1.Sum([]byte(source + "\n" + summary))
+	return hex.EncodeToString(sum[:16])
+}
+
+// icsZoneName t のタイムゾーンの IANA の名前. UTC や時差だけのタイムゾーンなど, 名前がないときは空文字列です.
+func icsZoneName(t time.Time) string {
+	name := t.Location().String()
+	if name == "Local" {
+		name = localZoneName()
+	}
+	if name == "" || name == "UTC" || name == "Local" {
+		return ""
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return ""
+	}
+	// MST のような略称から作ったタイムゾーンは時差が同じときだけ名前を使う
+	_, offset := t.Zone()
+	if _, o := t.In(loc).Zone(); o != offset {
+		return ""
+	}
+	return name
+}
+
+// localZoneName ローカルのタイムゾーンの名前を環境変数 TZ か /etc/localtime のリンク先から調べる.
+func localZoneName() string {
+	name, ok := os.LookupEnv("TZ")
+	if ok == false {
+		target, err := os.Readlink("/etc/localtime")
+		if err != nil {
+			return ""
+		}
+		name = target
+	}
+	name = strings.TrimPrefix(name, ":")
+	if i := strings.Index(name, "zoneinfo/"); i >= 0 {
+		name = name[i+len("zoneinfo/"):]
+	}
+	return name
+}
+
+// vtimezone from から to までの年の時差の切り替えを STANDARD と DAYLIGHT にした VTIMEZONE を作る.
+// 最初の要素はその年の 1 月 1 日の時差です.
+func vtimezone(tzid string, loc *time.Location, from, to time.Time) []string {
+	start := time.Date(from.In(loc).Year(), time.January, 1, 0, 0, 0, 0, loc)
+	end := time.Date(to.In(loc).Year()+1, time.January, 1, 0, 0, 0, 0, loc)
+
+	lines := []string{"BEGIN:VTIMEZONE", "TZID:" + tzid}
+	_, offset := start.Zone()
+	lines = append(lines, observance(start, offset)...)
+	for _, t := range zoneTransitions(start, end) {
+		_, before := t.Add(-time.Second).Zone()
+		lines = append(lines, observance(t, before)...)
+	}
+	return append(lines, "END:VTIMEZONE")
+}
+
+// observance t からの時差を STANDARD か DAYLIGHT にする. DTSTART は切り替える前の時差の時刻です.
+func observance(t time.Time, offsetFrom int) []string {
+	kind := "STANDARD"
+	if t.IsDST() {
+		kind = "DAYLIGHT"
+	}
+	name, offset := t.Zone()
+	onset := t.UTC().Add(time.Duration(offsetFrom) * time.Second)
+	return []string{
+		"BEGIN:" + kind,
+		"DTSTART:" + onset.Format(icsDateTimeLayout),
+		"TZOFFSETFROM:" + icsOffset(offsetFrom),
+		"TZOFFSETTO:" + icsOffset(offset),
+		"TZNAME:" + name,
+		"END:" + kind,
+	}
+}
+
+// zoneTransitions from から to までに時差か夏時間が切り替わる時刻. 1 日ごとに調べて, 切り替わった日の中を 1 秒まで二分探索します.
+func zoneTransitions(from, to time.Time) []time.Time {
+	same := func(a, b time.Time) bool {
+		_, x := a.Zone()
+		_, y := b.Zone()
+		return x == y && a.IsDST() == b.IsDST()
+	}
+
+	var result []time.Time
+	for t := from; t.Before(to); t = t.Add(24 * time.Hour) {
+		next := t.Add(24 * time.Hour)
+		if same(t, next) {
+			continue
+		}
+		lo, hi := t, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Second)
+			if same(t, mid) {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		result = append(result, hi)
+	}
+	return result
+}
+
+// icsOffset 時差を +0900 の形式にする. 秒があるときは +093915 のように秒もつけます.
+func icsOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	s := fmt.Sprintf("%c%02d%02d", sign, offset/3600, offset/60%60)
+	if offset%60 != 0 {
+		s += fmt.Sprintf("%02d", offset%60)
+	}
+	return s
+}
+
+// escapeICSText TEXT の値のバックスラッシュ, セミコロン, カンマ, 改行をエスケープする.
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICSLine 75 オクテットを超える行を, 文字の途中で切らないように CRLF と空白で折り返す.
+func foldICSLine(line string) string {
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		n := utf8.RuneLen(r)
+		if width+n > icsLineLength {
+			b.WriteString("\r\n ")
+			// 折り返した行は先頭の空白も数える
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	return b.String()
+}
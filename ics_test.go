@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFoldICSLine(t *testing.T) {
+	params := []struct {
+		line   string
+		expect string
+	}{
+		{line: "SUMMARY:short", expect: "SUMMARY:short"},
+		{line: strings.Repeat("a", 75), expect: strings.Repeat("a", 75)},
+		{line: strings.Repeat("a", 76), expect: strings.Repeat("a", 75) + "\r\n a"},
+		{line: strings.Repeat("a", 75+74+1), expect: strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 74) + "\r\n a"},
+		// 文字の途中では折り返さない
+		{line: strings.Repeat("a", 74) + "日本", expect: strings.Repeat("a", 74) + "\r\n 日本"},
+	}
+	for _, p := range params {
+		if actual := foldICSLine(p.line); actual != p.expect {
+			t.Errorf("foldICSLine(%q) = %q; want %q", p.line, actual, p.expect)
+		}
+	}
+}
+
+func TestEscapeICSText(t *testing.T) {
+	s := "a\\b;c,d\ne"
+	if actual, expect := escapeICSText(s), `a\\b\;c\,d\ne`; actual != expect {
+		t.Errorf("escapeICSText(%q) = %q; want %q", s, actual, expect)
+	}
+}
+
+func TestIcsOffset(t *testing.T) {
+	params := []struct {
+		offset int
+		expect string
+	}{
+		{offset: 9 * 3600, expect: "+0900"},
+		{offset: -(3*3600 + 30*60), expect: "-0330"},
+		{offset: 0, expect: "+0000"},
+		{offset: 9*3600 + 18*60 + 59, expect: "+091859"},
+	}
+	for _, p := range params {
+		if actual := icsOffset(p.offset); actual != p.expect {
+			t.Errorf("icsOffset(%d) = %s; want %s", p.offset, actual, p.expect)
+		}
+	}
+}
+
+func TestVtimezone(t *testing.T) {
+	loc, _ := time.LoadLocation("Australia/Sydney")
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, loc)
+	expect := []string{
+		"BEGIN:VTIMEZONE",
+		"TZID:Australia/Sydney",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:20240101T000000",
+		"TZOFFSETFROM:+1100",
+		"TZOFFSETTO:+1100",
+		"TZNAME:AEDT",
+		"END:DAYLIGHT",
+		"BEGIN:STANDARD",
+		"DTSTART:20240407T030000",
+		"TZOFFSETFROM:+1100",
+		"TZOFFSETTO:+1000",
+		"TZNAME:AEST",
+		"END:STANDARD",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:20241006T020000",
+		"TZOFFSETFROM:+1000",
+		"TZOFFSETTO:+1100",
+		"TZNAME:AEDT",
+		"END:DAYLIGHT",
+		"END:VTIMEZONE",
+	}
+	actual := vtimezone("Australia/Sydney", loc, from, from)
+	if strings.Join(actual, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("vtimezone() = %q; want %q", actual, expect)
+	}
+}
+
+func TestRun_ics(t *testing.T) {
+	nowInterface = &MyTime{}
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+
+	crlf := func(lines ...string) string {
+		return strings.Join(lines, "\r\n") + "\r\n"
+	}
+	header := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//ebc-2in2crc//dt//EN", "CALSCALE:GREGORIAN"}
+	tokyo := []string{"BEGIN:VTIMEZONE", "TZID:Asia/Tokyo", "BEGIN:STANDARD", "DTSTART:20240101T000000", "TZOFFSETFROM:+0900", "TZOFFSETTO:+0900", "TZNAME:JST", "END:STANDARD", "END:VTIMEZONE"}
+
+	params := []struct {
+		args   []string
+		input  string
+		expect string
+	}{
+		{
+			args: []string{AppName, "-o", "ics", "--now", "2024-01-01", "--summary", "Invoice due, pay", "--event-duration", "1h", "2024-01-31", "+1M"},
+			expect: crlf(append(append(header, tokyo...),
+				"BEGIN:VEVENT",
+				"UID:8dd327622fd43d8004d05e9871e9fbac@dt",
+				"DTSTAMP:20231231T150000Z",
+				"DTSTART;TZID=Asia/Tokyo:20240302T000000",
+				"DTEND;TZID=Asia/Tokyo:20240302T010000",
+				`SUMMARY:Invoice due\, pay`,
+				"END:VEVENT",
+				"END:VCALENDAR")...),
+		},
+		{
+			// タイムゾーンの名前がないときは UTC
+			args: []string{AppName, "-o", "ics", "--now", "2024-01-01", "2024-01-01T00:00:00+05:30"},
+			expect: crlf(append(header,
+				"BEGIN:VEVENT",
+				"UID:1e45bd52d2f576a45cb2bdc2f0f2d74a@dt",
+				"DTSTAMP:20231231T150000Z",
+				"DTSTART:20231231T183000Z",
+				"END:VEVENT",
+				"END:VCALENDAR")...),
+		},
+		{
+			args:  []string{AppName, "ics", "--now", "2024-01-01", "--all-day", "--event-duration", "P2D", "--summary", "Deadline", "--", "-1D"},
+			input: "2024-01-31 + 1M\tInvoice\n\n2024-02-10\n",
+			expect: crlf(append(header,
+				"BEGIN:VEVENT",
+				"UID:52e085cfcdd985e0957f50af1ae16ddf@dt",
+				"DTSTAMP:20231231T150000Z",
+				"DTSTART;VALUE=DATE:20240301",
+				"DTEND;VALUE=DATE:20240303",
+				"SUMMARY:Invoice",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"UID:582bd90cc68f1ced9b7ca1c0d0d41cad@dt",
+				"DTSTAMP:20231231T150000Z",
+				"DTSTART;VALUE=DATE:20240209",
+				"DTEND;VALUE=DATE:20240211",
+				"SUMMARY:Deadline",
+				"END:VEVENT",
+				"END:VCALENDAR")...),
+		},
+	}
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{inStream: strings.NewReader(p.input), outStream: outStream, errStream: errStream}
+		if status := clo.Run(p.args); status != ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != p.expect {
+			t.Errorf("Run(%s): Output = %q; want %q", p.args, outStream.String(), p.expect)
+		}
+	}
+
+	// UID は計算式か --uid と件名から作るので, 計算した日時が変わっても変わらない
+	firstUID := func(args ...string) string {
+		outStream := new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: new(bytes.Buffer)}
+		clo.Run(append([]string{AppName, "-o", "ics", "--summary", "Invoice"}, args...))
+		for _, line := range strings.Split(outStream.String(), "\r\n") {
+			if strings.HasPrefix(line, "UID:") {
+				return line
+			}
+		}
+		return ""
+	}
+	if a, b := firstUID("2024-01-31", "+1M"), firstUID("-a", "2024-01-31", "+1M"); a == "" || a != b {
+		t.Errorf("Run(-o ics): UID = %q, %q; want the same UID", a, b)
+	}
+	if a, b := firstUID("--uid", "invoice", "2024-01-31", "+1M"), firstUID("--uid", "invoice", "2024-02-29"); a == "" || a != b {
+		t.Errorf("Run(-o ics --uid): UID = %q, %q; want the same UID", a, b)
+	}
+	if a, b := firstUID("2024-01-31", "+1M"), firstUID("2024-01-31", "+2M"); a == b {
+		t.Errorf("Run(-o ics): UID = %q, %q; want different UIDs", a, b)
+	}
+
+	// 同じ計算式と件名の予定の UID は重ならず, cron の結果はひとつのカレンダーになる
+	outStream := new(bytes.Buffer)
+	clo := &CLO{inStream: strings.NewReader("2024-01-01\n2024-01-01\n"), outStream: outStream, errStream: new(bytes.Buffer)}
+	clo.Run([]string{AppName, "ics"})
+	if uid := "UID:b33a9772bafecc6429b4df0e09d8026f-2@dt"; strings.Count(outStream.String(), "BEGIN:VEVENT") != 2 || strings.Contains(outStream.String(), uid) == false {
+		t.Errorf("Run(ics): Output = %q; want 2 events with %s", outStream.String(), uid)
+	}
+	outStream.Reset()
+	clo = &CLO{outStream: outStream, errStream: new(bytes.Buffer)}
+	clo.Run([]string{AppName, "cron", "-o", "ics", "--next", "3", "0 9 * * *", "2024-01-01"})
+	if s := outStream.String(); strings.Count(s, "BEGIN:VCALENDAR") != 1 || strings.Count(s, "BEGIN:VEVENT") != 3 {
+		t.Errorf("Run(cron -o ics): Output = %q; want 1 calendar with 3 events", s)
+	}
+
+	// cron や rrule の UID はその回の日時から作るので, 計算の元の日時を変えても同じ回は同じ UID
+	uids := func(args ...string) []string {
+		outStream := new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: new(bytes.Buffer)}
+		clo.Run(append([]string{AppName, "-o", "ics"}, args...))
+		var uids []string
+		for _, line := range strings.Split(outStream.String(), "\r\n") {
+			if strings.HasPrefix(line, "UID:") {
+				uids = append(uids, line)
+			}
+		}
+		return uids
+	}
+	for _, args := range [][]string{
+		{"cron", "--next", "3", "0 9 * * *"},
+		{"rrule", "--limit", "3", "FREQ=DAILY"},
+		{"--uid", "standup", "rrule", "--limit", "3", "FREQ=DAILY"},
+	} {
+		a := uids(append(args, "2024-01-01 09:00:00")...)
+		b := uids(append(args, "2024-01-02 09:00:00")...)
+		if len(a) != 3 || len(b) != 3 || reflect.DeepEqual(a[1:], b[:2]) == false || a[0] == a[1] {
+			t.Errorf("Run(%s): UID = %q, %q; want the same UIDs for the same occurrences", args, a, b)
+		}
+	}
+
+	errors := []struct {
+		args   []string
+		input  string
+		expect string
+	}{
+		{args: []string{AppName, "-o", "ics", "1h"}, expect: "-o ics needs a date."},
+		{args: []string{AppName, "-o", "ics", "--zones", "UTC,Asia/Tokyo", "now"}, expect: "-o ics cannot be used here."},
+		{args: []string{AppName, "-o", "ics", "--all-day", "--event-duration", "3h", "now"}, expect: "'3h' is invalid duration for --all-day."},
+		{args: []string{AppName, "-o", "ics", "--event-duration", "1M", "now"}, expect: "'1M' is invalid duration."},
+		{args: []string{AppName, "ics"}, input: "2024-01-31\nsomeday\n", expect: "line 2: 'someday' is invalid format."},
+		{args: []string{AppName, "ics"}, input: "1D\n", expect: "line 1: '1D' is not a date."},
+	}
+	for _, p := range errors {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{inStream: strings.NewReader(p.input), outStream: outStream, errStream: errStream}
+		if status := clo.Run(p.args); status == ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want error", p.args, status)
+		}
+		if strings.Contains(errStream.String(), p.expect) == false {
+			t.Errorf("Run(%s): Error = %v; want %v", p.args, errStream.String(), p.expect)
+		}
+	}
+}
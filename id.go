@@ -0,0 +1,206 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ulidFormat      = "ulid"
+	uuidv7Format    = "uuidv7"
+	objectIDFormat  = "objectid"
+	ksuidFormat     = "ksuid"
+	snowflakeFormat = "snowflake"
+
+	// randomSuffix 乱数部分をランダムにするときに ID フォーマットの末尾につける
+	randomSuffix = "-rand"
+
+	// ksuidEpoch KSUID のエポック (2014-05-13T16:53:20Z)
+	ksuidEpoch = 1400000000
+	// twitterEpoch Snowflake のデフォルトのエポック (ミリ秒)
+	twitterEpoch = 1288834974657
+
+	defaultWorkerBits = 10
+	sequenceBits      = 12
+
+	crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	base62          = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// formatID ID フォーマットのときは t を ID に変換する.
+// ID フォーマットでないときは false を返します. ID フォーマットでも, パラメーターが
+// 正しくないときや t が ID で表せる範囲の外のときはエラーです.
+//
+// 利用できるフォーマットは ulid, uuidv7, objectid, ksuid, snowflake です.
+// 末尾に -rand をつけると乱数部分をランダムにします. つけないときは乱数部分を
+// ゼロにした, その時刻における最小の ID になります.
+// snowflake は snowflake:<エポックミリ秒>:<ワーカービット数> でエポックとワーカー ID の
+// ビット数を指定できます.
+func formatID(t time.Time, f string) (string, bool, error) {
+	name, params := f, ""
+	hasParams := false
+	if i := strings.Index(f, ":"); i >= 0 {
+		name, params, hasParams = f[:i], f[i+1:], true
+	}
+	random := strings.HasSuffix(name, randomSuffix)
+	name = strings.TrimSuffix(name, randomSuffix)
+
+	switch name {
+	case ulidFormat, uuidv7Format, objectIDFormat, ksuidFormat, snowflakeFormat:
+	default:
+		return "", false, nil
+	}
+	if name != snowflakeFormat && hasParams {
+		return "", true, fmt.Errorf("'%s' is invalid format.", f)
+	}
+
+	// value ID の時刻の部分. 0 以上 max 未満でなければ表せない
+	var value, max int64
+	var epoch int64
+	var workerBits int
+	switch name {
+	case ulidFormat, uuidv7Format:
+		value, max = unixMilli(t), 1<<48
+	case objectIDFormat:
+		value, max = t.Unix(), 1<<32
+	case ksuidFormat:
+		value, max = t.Unix()-ksuidEpoch, 1<<32
+	case snowflakeFormat:
+		var err error
+		if epoch, workerBits, err = parseSnowflakeParams(params); err != nil {
+			return "", true, fmt.Errorf("'%s' is invalid format.", f)
+		}
+		value, max = unixMilli(t)-epoch, 1<<uint(63-workerBits-sequenceBits)
+	}
+	if value < 0 || value >= max {
+		return "", true, fmt.Errorf("'%s' is out of range for %s.", t.Format(time.RFC3339), name)
+	}
+
+	switch name {
+	case ulidFormat:
+		return ulid(t, random), true, nil
+	case uuidv7Format:
+		return uuidv7(t, random), true, nil
+	case objectIDFormat:
+		return objectID(t, random), true, nil
+	case ksuidFormat:
+		return ksuid(t, random), true, nil
+	default:
+		return snowflake(t, random, epoch, workerBits), true, nil
+	}
+}
+
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func randomBytes(n int, random bool) []byte {
+	b := make([]byte, n)
+	if random {
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+	}
+	return b
+}
+
+func ulid(t time.Time, random bool) string {
+	b := make([]byte, 16)
+	ms := uint64(unixMilli(t))
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> uint(8*(5-i)))
+	}
+	copy(b[6:], randomBytes(10, random))
+
+	// 128 ビットを 5 ビットずつ 26 文字にエンコードする (先頭 2 ビットはパディング)
+	n := new(big.Int).SetBytes(b)
+	out := make([]byte, 26)
+	mask := big.NewInt(31)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordBase32[new(big.Int).And(n, mask).Int64()]
+		n.Rsh(n, 5)
+	}
+	return string(out)
+}
+
+func uuidv7(t time.Time, random bool) string {
+	b := make([]byte, 16)
+	ms := uint64(unixMilli(t))
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> uint(8*(5-i)))
+	}
+	copy(b[6:], randomBytes(10, random))
+	b[6] = b[6]&0x0f | 0x70
+	b[8] = b[8]&0x3f | 0x80
+
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+func objectID(t time.Time, random bool) string {
+	b := make([]byte, 12)
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()))
+	copy(b[4:], randomBytes(8, random))
+	return hex.EncodeToString(b)
+}
+
+func ksuid(t time.Time, random bool) string {
+	b := make([]byte, 20)
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()-ksuidEpoch))
+	copy(b[4:], randomBytes(16, random))
+
+	n := new(big.Int).SetBytes(b)
+	out := make([]byte, 27)
+	base := big.NewInt(62)
+	mod := new(big.Int)
+	for i := 26; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		out[i] = base62[mod.Int64()]
+	}
+	return string(out)
+}
+
+func parseSnowflakeParams(params string) (int64, int, error) {
+	epoch, workerBits := int64(twitterEpoch), defaultWorkerBits
+	if params == "" {
+		return epoch, workerBits, nil
+	}
+
+	cols := strings.Split(params, ":")
+	if len(cols) > 2 {
+		return 0, 0, fmt.Errorf("'%s' is invalid snowflake parameter", params)
+	}
+	var err error
+	if cols[0] != "" {
+		epoch, err = strconv.ParseInt(cols[0], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if len(cols) == 2 && cols[1] != "" {
+		workerBits, err = strconv.Atoi(cols[1])
+		if err != nil {
+			return 0, 0, err
+		}
+		if workerBits < 0 || workerBits+sequenceBits > 62 {
+			return 0, 0, fmt.Errorf("'%d' is invalid worker bits", workerBits)
+		}
+	}
+	return epoch, workerBits, nil
+}
+
+func snowflake(t time.Time, random bool, epoch int64, workerBits int) string {
+	shift := uint(workerBits + sequenceBits)
+	id := (unixMilli(t) - epoch) << shift
+	if random {
+		r := int64(binary.BigEndian.Uint64(randomBytes(8, true)))
+		id |= r & (1<<shift - 1)
+	}
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatID(t *testing.T) {
+	tm := time.Unix(1526113800, 0)
+	params := []struct {
+		format string
+		expect string
+	}{
+		{format: "ulid", expect: "01CD9QH3T00000000000000000"},
+		{format: "uuidv7", expect: "01635378-8f40-7000-8000-000000000000"},
+		{format: "objectid", expect: "5af6a6080000000000000000"},
+		{format: "ksuid", expect: "14Ut2REnfmy1fmaokXgEIXZuMWO"},
+		{format: "snowflake", expect: "995219526251446272"},
+		{format: "snowflake:0:5", expect: "200030787993600000"},
+	}
+
+	for _, p := range params {
+		actual, ok, err := formatID(tm, p.format)
+		if !ok || err != nil || actual != p.expect {
+			t.Errorf("formatID(%s) = %s, %v, %v; want %s", p.format, actual, ok, err, p.expect)
+		}
+	}
+}
+
+func TestFormatID_random(t *testing.T) {
+	tm := time.Unix(1526113800, 0)
+	params := []struct {
+		format string
+		prefix string
+		length int
+	}{
+		{format: "ulid-rand", prefix: "01CD9QH3T0", length: 26},
+		{format: "uuidv7-rand", prefix: "01635378-8f40-7", length: 36},
+		{format: "objectid-rand", prefix: "5af6a608", length: 24},
+		{format: "ksuid-rand", prefix: "", length: 27},
+	}
+
+	for _, p := range params {
+		actual, ok, _ := formatID(tm, p.format)
+		if !ok || len(actual) != p.length || strings.HasPrefix(actual, p.prefix) == false {
+			t.Errorf("formatID(%s) = %s, %v; want prefix %s", p.format, actual, ok, p.prefix)
+		}
+		min, _, _ := formatID(tm, strings.TrimSuffix(p.format, randomSuffix))
+		if actual < min {
+			t.Errorf("formatID(%s) = %s; want greater than or equal to %s", p.format, actual, min)
+		}
+	}
+}
+
+func TestFormatID_notID(t *testing.T) {
+	params := []string{"2006-01-02", "ulidx", "Mon Jan _2 15:04:05 2006"}
+
+	for _, p := range params {
+		if _, ok, err := formatID(time.Unix(0, 0), p); ok || err != nil {
+			t.Errorf("formatID(%s) = %v, %v; want false, nil", p, ok, err)
+		}
+	}
+}
+
+func TestFormatID_error(t *testing.T) {
+	params := []struct {
+		time   time.Time
+		format string
+		expect string
+	}{
+		{time: time.Unix(0, 0), format: "ulid:1", expect: "'ulid:1' is invalid format."},
+		{time: time.Unix(0, 0), format: "snowflake:x", expect: "'snowflake:x' is invalid format."},
+		{time: time.Unix(0, 0), format: "snowflake:0:60", expect: "'snowflake:0:60' is invalid format."},
+		{time: time.Unix(0, 0), format: "snowflake:0:1:2", expect: "'snowflake:0:1:2' is invalid format."},
+		// エポックより前
+		{time: time.Unix(ksuidEpoch-1, 0).UTC(), format: "ksuid", expect: "'2014-05-13T16:53:19Z' is out of range for ksuid."},
+		{time: time.Unix(0, 0).UTC(), format: "snowflake", expect: "'1970-01-01T00:00:00Z' is out of range for snowflake."},
+		{time: time.Unix(-1, 0).UTC(), format: "objectid-rand", expect: "'1969-12-31T23:59:59Z' is out of range for objectid."},
+		{time: time.Unix(-1, 0).UTC(), format: "ulid", expect: "'1969-12-31T23:59:59Z' is out of range for ulid."},
+		// 時刻の部分のビット数を超える
+		{time: time.Unix(1<<32, 0).UTC(), format: "objectid", expect: "'2106-02-07T06:28:16Z' is out of range for objectid."},
+	}
+
+	for _, p := range params {
+		if _, ok, err := formatID(p.time, p.format); ok == false || err == nil || err.Error() != p.expect {
+			t.Errorf("formatID(%v, %s) = %v, %v; want %s", p.time, p.format, ok, err, p.expect)
+		}
+	}
+}
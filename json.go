@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+func jsonCommand() cli.Command {
+	return cli.Command{
+		Name:      "json",
+		Usage:     "JSON や NDJSON のフィールドの日時を変換します",
+		UsageText: AppName + " json [options]",
+		Description: `標準入力の JSON を読み, --path のフィールドの日時を --expr の計算式と -o のフォーマットで変換して出力します.
+   キーの順番や他の値, 空白は入力のまま出力します. 文字列と数値 (unix 時間) のどちらも変換できます.
+   -o が unix か unixm のときと, -o を省略して値が数値のときは数値として, それ以外は文字列として出力します.
+   パスは .ts や .meta.created, .items[0].ts のように指定します. .items[].ts は配列のすべての要素です.`,
+		HideHelp: true,
+		Flags: commandFlags(
+			cli.StringSliceFlag{
+				Name:  "path, p",
+				Usage: "変換するフィールドのパスを指定します. 複数指定できます",
+			},
+			cli.StringSliceFlag{
+				Name:  "expr, e",
+				Usage: "日時に続けて評価する計算式を指定します. 複数指定できます",
+			},
+		),
+		Action: commandAction(jsonTransform),
+	}
+}
+
+// jsonStep パスの 1 要素. key が空のときは配列の要素で, index が -1 のときはすべての要素です.
+type jsonStep struct {
+	key   string
+	index int
+}
+
+var jsonStepRegexp = regexp.MustCompile(`^(?:\.([^.\[\]]+)|\[(\d*)\])`)
+
+// parseJSONPath .meta.created や .items[0].ts のようなパスを解釈する.
+func parseJSONPath(path string) ([]jsonStep, error) {
+	var steps []jsonStep
+	for s := path; s != ""; {
+		m := jsonStepRegexp.FindStringSubmatch(s)
+		if m == nil {
+			return nil, fmt.Errorf("'%s' is invalid path.", path)
+		}
+		switch {
+		case m[1] != "":
+			steps = append(steps, jsonStep{key: m[1]})
+		case m[2] == "":
+			steps = append(steps, jsonStep{index: -1})
+		default:
+			n, _ := strconv.Atoi(m[2])
+			steps = append(steps, jsonStep{index: n})
+		}
+		s = s[len(m[0]):]
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("'%s' is invalid path.", path)
+	}
+	return steps, nil
+}
+
+// jsonSpan 文書の中の値の位置
+type jsonSpan struct {
+	start, end int
+}
+
+// jsonFinder 文書の中からパスの値を探す. 文書は正しい JSON であることを前提にします.
+type jsonFinder struct {
+	doc []byte
+}
+
+func (f *jsonFinder) skipSpace(i int) int {
+	for i < len(f.doc) && strings.IndexByte(" \t\r\n", f.doc[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// valueEnd i から始まる値の終わりの位置
+func (f *jsonFinder) valueEnd(i int) int {
+	switch f.doc[i] {
+	case '"':
+		for i++; f.doc[i] != '"'; i++ {
+			if f.doc[i] == '\\' {
+				i++
+			}
+		}
+		return i + 1
+	case '{', '[':
+		depth := 0
+		for ; ; i++ {
+			switch f.doc[i] {
+			case '"':
+				i = f.valueEnd(i) - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+	default:
+		for i < len(f.doc) && strings.IndexByte(",}] \t\r\n", f.doc[i]) < 0 {
+			i++
+		}
+		return i
+	}
+}
+
+// find i から始まる値の中で steps に一致する値の位置を集める.
+func (f *jsonFinder) find(i int, steps []jsonStep, spans []jsonSpan) []jsonSpan {
+	i = f.skipSpace(i)
+	if len(steps) == 0 {
+		return append(spans, jsonSpan{start: i, end: f.valueEnd(i)})
+	}
+	step := steps[0]
+
+	switch {
+	case f.doc[i] == '{' && step.key != "":
+		i = f.skipSpace(i + 1)
+		for f.doc[i] != '}' {
+			end := f.valueEnd(i)
+			var key string
+			json.Unmarshal(f.doc[i:end], &key)
+			i = f.skipSpace(end) + 1 // ':'
+			i = f.skipSpace(i)
+			if key == step.key {
+				spans = f.find(i, steps[1:], spans)
+			}
+			i = f.skipSpace(f.valueEnd(i))
+			if f.doc[i] == ',' {
+				i = f.skipSpace(i + 1)
+			}
+		}
+	case f.doc[i] == '[' && step.key == "":
+		i = f.skipSpace(i + 1)
+		for n := 0; f.doc[i] != ']'; n++ {
+			if step.index < 0 || step.index == n {
+				spans = f.find(i, steps[1:], spans)
+			}
+			i = f.skipSpace(f.valueEnd(i))
+			if f.doc[i] == ',' {
+				i = f.skipSpace(i + 1)
+			}
+		}
+	}
+	return spans
+}
+
+// jsonTransformer フィールドの日時を変換する
+type jsonTransformer struct {
+	paths []string
+	steps [][]jsonStep
+	exprs []string
+	// numeric -o が unix 秒か unix ミリ秒で, 結果を数値で出力する
+	numeric bool
+	// keepType -o がないときは入力と同じフォーマットなので, 数値は数値のまま出力する
+	keepType bool
+	// failed 変換できなかったフィールドの数
+	failed int
+}
+
+func jsonTransform(c *cli.Context) error {
+	t := &jsonTransformer{exprs: c.StringSlice("expr")}
+	for _, p := range c.StringSlice("path") {
+		if containsString(t.paths, p) {
+			continue
+		}
+		steps, err := parseJSONPath(p)
+		if err != nil {
+			return err
+		}
+		t.paths = append(t.paths, p)
+		t.steps = append(t.steps, steps)
+	}
+	if len(t.steps) == 0 {
+		return errors.New("json needs --path.")
+	}
+
+	outputFormat := c.String("o")
+	if v, ok := formats[outputFormat]; ok {
+		outputFormat = v
+	}
+	t.numeric = outputFormat == unixSeconds || outputFormat == unixMilliSeconds
+	t.keepType = outputFormat == ""
+
+	if err := t.run(clo.inStream, clo.outStream); err != nil {
+		return err
+	}
+	if t.failed > 0 {
+		return evalError(fmt.Errorf("could not convert %d values.", t.failed))
+	}
+	return nil
+}
+
+// run 1 行ずつ読み, JSON として完結したところで変換して出力する.
+// 複数行にわたる JSON も読めます. 変換できないフィールドや JSON として正しくない行はそのまま出力し,
+// 行番号とともに報告します.
+func (t *jsonTransformer) run(in io.Reader, out io.Writer) error {
+	reader := bufio.NewReaderSize(in, 64*1024)
+	writer := bufio.NewWriterSize(out, 64*1024)
+	defer writer.Flush()
+
+	var doc []byte
+	line, start := 0, 0
+	for {
+		s, err := reader.ReadBytes('\n')
+		if len(s) > 0 {
+			line++
+			if len(doc) == 0 {
+				start = line
+			}
+			doc = append(doc, s...)
+		}
+
+		if len(bytes.TrimSpace(doc)) == 0 {
+			// 空行はそのまま出力する
+			if _, werr := writer.Write(doc); werr != nil {
+				return werr
+			}
+			doc = doc[:0]
+		} else {
+			complete, invalid := jsonComplete(doc)
+			if invalid || (complete == false && err == io.EOF) {
+				t.failed++
+				fmt.Fprintf(clo.errStream, "line %d: invalid JSON.\n", start)
+			}
+			if complete {
+				doc = t.document(doc, start)
+			}
+			if complete || invalid {
+				if _, werr := writer.Write(doc); werr != nil {
+					return werr
+				}
+				doc = doc[:0]
+			}
+		}
+		if err == io.EOF {
+			_, werr := writer.Write(doc)
+			return werr
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// jsonComplete doc がひとつの JSON として完結しているか, 続きを読んでも正しくならないかを調べる.
+func jsonComplete(doc []byte) (complete, invalid bool) {
+	var raw json.RawMessage
+	err := json.Unmarshal(doc, &raw)
+	if err == nil {
+		return true, false
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) && syntaxErr.Offset >= int64(len(doc)) {
+		// 入力の終わりまで正しいので続きがある
+		return false, false
+	}
+	return false, true
+}
+
+// document ひとつの文書のフィールドを変換する. line は文書の始まりの行番号.
+func (t *jsonTransformer) document(doc []byte, line int) []byte {
+	f := &jsonFinder{doc: doc}
+	type replacement struct {
+		jsonSpan
+		value []byte
+	}
+	var replacements []replacement
+	for i, steps := range t.steps {
+		for _, span := range f.find(0, steps, nil) {
+			raw := doc[span.start:span.end]
+			value, err := t.convert(raw)
+			if err != nil {
+				t.failed++
+				fmt.Fprintf(clo.errStream, "line %d: %s: %v\n", line+bytes.Count(doc[:span.start], []byte("\n")), t.paths[i], err)
+				continue
+			}
+			if value != nil {
+				replacements = append(replacements, replacement{jsonSpan: span, value: value})
+			}
+		}
+	}
+	if len(replacements) == 0 {
+		return doc
+	}
+
+	// 後ろから置き換えると前の位置が変わらない. 同じ値を複数のパスで指定したときは最初のものを使う.
+	sort.SliceStable(replacements, func(i, j int) bool {
+		return replacements[i].start < replacements[j].start
+	})
+	result := append([]byte(nil), doc...)
+	for i := len(replacements) - 1; i >= 0; i-- {
+		r := replacements[i]
+		if i > 0 && replacements[i-1].start == r.start {
+			continue
+		}
+		result = append(result[:r.start], append(r.value, result[r.end:]...)...)
+	}
+	return result
+}
+
+// convert 文字列か数値の値を変換する. null は変換しないので nil を返します.
+func (t *jsonTransformer) convert(raw []byte) ([]byte, error) {
+	var s string
+	switch raw[0] {
+	case 'n':
+		return nil, nil
+	case '"':
+		json.Unmarshal(raw, &s)
+	case '{', '[', 't', 'f':
+		return nil, fmt.Errorf("'%s' is not a string or a number.", raw)
+	default:
+		s = string(raw)
+	}
+
+	out, err := transformDate(s, t.exprs)
+	if err != nil {
+		return nil, err
+	}
+	if t.numeric || (t.keepType && raw[0] != '"' && json.Valid([]byte(out))) {
+		return []byte(out), nil
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(out)
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	params := []struct {
+		path   string
+		expect []jsonStep
+	}{
+		{path: ".ts", expect: []jsonStep{{key: "ts"}}},
+		{path: ".meta.created", expect: []jsonStep{{key: "meta"}, {key: "created"}}},
+		{path: ".items[0].ts", expect: []jsonStep{{key: "items"}, {index: 0}, {key: "ts"}}},
+		{path: ".items[].ts", expect: []jsonStep{{key: "items"}, {index: -1}, {key: "ts"}}},
+	}
+
+	for _, p := range params {
+		actual, err := parseJSONPath(p.path)
+		if err != nil {
+			t.Fatalf("parseJSONPath(%s) = %v", p.path, err)
+		}
+		if len(actual) != len(p.expect) {
+			t.Fatalf("parseJSONPath(%s) = %v; want %v", p.path, actual, p.expect)
+		}
+		for i := range actual {
+			if actual[i] != p.expect[i] {
+				t.Errorf("parseJSONPath(%s) = %v; want %v", p.path, actual, p.expect)
+			}
+		}
+	}
+
+	for _, path := range []string{"", "ts", ".a..b", ".a[x]"} {
+		if _, err := parseJSONPath(path); err == nil {
+			t.Errorf("parseJSONPath(%s) = nil; want error", path)
+		}
+	}
+}
+
+func TestRun_json(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	input := strings.Join([]string{
+		`{"id":1,"ts":"2024-01-31T23:30:00+09:00","meta":{"created":1706659200,"x":[1,"]"]},"n":null}`,
+		``,
+		`{"ts" : "2024-02-01 00:00:00", "items":[{"ts":"2024-02-01"},{"ts":"2024-02-02"}], "z":"<&>"}`,
+		`{`,
+		`  "ts": "2024-01-31 10:00:00"`,
+		`}`,
+	}, "\n") + "\n"
+
+	params := []struct {
+		args   []string
+		expect []string
+	}{
+		{
+			args: []string{"--path", ".ts", "--path", ".meta.created", "-o", "unixm"},
+			expect: []string{
+				`{"id":1,"ts":1706711400000,"meta":{"created":1706659200000,"x":[1,"]"]},"n":null}`,
+				``,
+				`{"ts" : 1706713200000, "items":[{"ts":"2024-02-01"},{"ts":"2024-02-02"}], "z":"<&>"}`,
+				`{`,
+				`  "ts": 1706662800000`,
+				`}`,
+			},
+		},
+		{
+			// -o がないときは数値は数値, 文字列は文字列のまま
+			args: []string{"--path", ".ts", "--path", ".meta.created", "--expr", "+1D"},
+			expect: []string{
+				`{"id":1,"ts":"2024-02-01T23:30:00+09:00","meta":{"created":1706745600,"x":[1,"]"]},"n":null}`,
+				``,
+				`{"ts" : "2024-02-02 00:00:00", "items":[{"ts":"2024-02-01"},{"ts":"2024-02-02"}], "z":"<&>"}`,
+				`{`,
+				`  "ts": "2024-02-01 10:00:00"`,
+				`}`,
+			},
+		},
+		{
+			args: []string{"-p", ".items[].ts", "-p", ".n", "--expr", "+1D", "-o", "YMD/"},
+			expect: []string{
+				`{"id":1,"ts":"2024-01-31T23:30:00+09:00","meta":{"created":1706659200,"x":[1,"]"]},"n":null}`,
+				``,
+				`{"ts" : "2024-02-01 00:00:00", "items":[{"ts":"2024/02/02"},{"ts":"2024/02/03"}], "z":"<&>"}`,
+				`{`,
+				`  "ts": "2024-01-31 10:00:00"`,
+				`}`,
+			},
+		},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := clo.Run(append([]string{AppName, "json"}, p.args...))
+		if status != ExitCodeOK {
+			t.Fatalf("Run(%v): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream)
+		}
+		expect := strings.Join(p.expect, "\n") + "\n"
+		if outStream.String() != expect {
+			t.Errorf("Run(%v): Output = %q; want %q", p.args, outStream, expect)
+		}
+	}
+}
+
+func TestRun_jsonError(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	input := strings.Join([]string{
+		`{"ts":"2024-01-31"}`,
+		`{"ts":"bad"}`,
+		`not json`,
+		`{"ts":true}`,
+	}, "\n") + "\n"
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	clo := &CLO{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+	status := clo.Run([]string{AppName, "json", "-p", ".ts", "-o", "YMD/"})
+	if status != ExitCodeError {
+		t.Errorf("Run(json): ExitStatus = %d; want %d", status, ExitCodeError)
+	}
+	expect := `{"ts":"2024/01/31"}` + "\n" + strings.Join(strings.Split(input, "\n")[1:], "\n")
+	if outStream.String() != expect {
+		t.Errorf("Run(json): Output = %q; want %q", outStream, expect)
+	}
+	for _, e := range []string{"line 2: .ts: 'bad' is invalid format.", "line 3: invalid JSON.", "line 4: .ts: 'true' is not a string or a number.", "could not convert 3 values."} {
+		if strings.Contains(errStream.String(), e) == false {
+			t.Errorf("Run(json): Error = %q; want %q", errStream, e)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	leapSecondFileName = "leap-seconds.list"
+	gpsSeconds         = "gps"
+	// ntpEpochOffset 1900-01-01 (NTP の基準) から 1970-01-01 までの秒数
+	ntpEpochOffset = 2208988800
+	// gpsOffset TAI と GPS 時刻の差
+	gpsOffset = 19 * time.Second
+)
+
+// gpsEpoch GPS 時刻の基準
+var gpsEpoch = time.Date(1980, time.January, 6, 0, 0, 0, 0, time.UTC)
+
+// TimeScale 時刻系
+type TimeScale int
+
+const (
+	// ScaleUTC 協定世界時. うるう秒があります
+	ScaleUTC TimeScale = iota
+	// ScaleTAI 国際原子時. UTC よりうるう秒の合計だけ進んでいます
+	ScaleTAI
+	// ScaleGPS GPS 時刻. TAI より 19 秒遅れています
+	ScaleGPS
+)
+
+// inputScale 入力の日時の時刻系
+var inputScale = ScaleUTC
+
+// outputScale 出力する日時の時刻系
+var outputScale = ScaleUTC
+
+// showLeapSecond うるう秒を 23:59:60 のように表示するか
+var showLeapSecond = false
+
+// leapSecond この日時 (UTC) から TAI - UTC が offset 秒になる
+type leapSecond struct {
+	start  time.Time
+	offset int
+}
+
+// builtinLeapSeconds 組み込みのうるう秒の表. 2017-01-01 から 37 秒です.
+var builtinLeapSeconds = []leapSecond{
+	{start: time.Date(1972, 1, 1, 0, 0, 0, 0, time.UTC), offset: 10},
+	{start: time.Date(1972, 7, 1, 0, 0, 0, 0, time.UTC), offset: 11},
+	{start: time.Date(1973, 1, 1, 0, 0, 0, 0, time.UTC), offset: 12},
+	{start: time.Date(1974, 1, 1, 0, 0, 0, 0, time.UTC), offset: 13},
+	{start: time.Date(1975, 1, 1, 0, 0, 0, 0, time.UTC), offset: 14},
+	{start: time.Date(1976, 1, 1, 0, 0, 0, 0, time.UTC), offset: 15},
+	{start: time.Date(1977, 1, 1, 0, 0, 0, 0, time.UTC), offset: 16},
+	{start: time.Date(1978, 1, 1, 0, 0, 0, 0, time.UTC), offset: 17},
+	{start: time.Date(1979, 1, 1, 0, 0, 0, 0, time.UTC), offset: 18},
+	{start: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC), offset: 19},
+	{start: time.Date(1981, 7, 1, 0, 0, 0, 0, time.UTC), offset: 20},
+	{start: time.Date(1982, 7, 1, 0, 0, 0, 0, time.UTC), offset: 21},
+	{start: time.Date(1983, 7, 1, 0, 0, 0, 0, time.UTC), offset: 22},
+	{start: time.Date(1985, 7, 1, 0, 0, 0, 0, time.UTC), offset: 23},
+	{start: time.Date(1988, 1, 1, 0, 0, 0, 0, time.UTC), offset: 24},
+	{start: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), offset: 25},
+	{start: time.Date(1991, 1, 1, 0, 0, 0, 0, time.UTC), offset: 26},
+	{start: time.Date(1992, 7, 1, 0, 0, 0, 0, time.UTC), offset: 27},
+	{start: time.Date(1993, 7, 1, 0, 0, 0, 0, time.UTC), offset: 28},
+	{start: time.Date(1994, 7, 1, 0, 0, 0, 0, time.UTC), offset: 29},
+	{start: time.Date(1996, 1, 1, 0, 0, 0, 0, time.UTC), offset: 30},
+	{start: time.Date(1997, 7, 1, 0, 0, 0, 0, time.UTC), offset: 31},
+	{start: time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC), offset: 32},
+	{start: time.Date(2006, 1, 1, 0, 0, 0, 0, time.UTC), offset: 33},
+	{start: time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC), offset: 34},
+	{start: time.Date(2012, 7, 1, 0, 0, 0, 0, time.UTC), offset: 35},
+	{start: time.Date(2015, 7, 1, 0, 0, 0, 0, time.UTC), offset: 36},
+	{start: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), offset: 37},
+}
+
+// leapSeconds 使用するうるう秒の表. 古い順に並んでいます.
+var leapSeconds = builtinLeapSeconds
+
+func parseTimeScale(s string) (TimeScale, error) {
+	switch s {
+	case "utc":
+		return ScaleUTC, nil
+	case "tai":
+		return ScaleTAI, nil
+	case "gps":
+		return ScaleGPS, nil
+	default:
+		return ScaleUTC, fmt.Errorf("'%s' is invalid time scale.", s)
+	}
+}
+
+// loadLeapSeconds うるう秒の表を読む. path が空のときは設定ディレクトリの leap-seconds.list を読み,
+// なければ組み込みの表を使います. ファイルは IETF の leap-seconds.list の形式で,
+// 1 行にひとつ 1900-01-01 からの秒数と TAI - UTC を書きます. # から行末まではコメントです.
+func loadLeapSeconds(path string) error {
+	leapSeconds = builtinLeapSeconds
+
+	optional := path == ""
+	if optional {
+		dir, err := configDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(dir, leapSecondFileName)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if optional {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	var table []leapSecond
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) < 2 {
+			return fmt.Errorf("%s:%d: '%s' is invalid leap second.", path, n, strings.TrimSpace(line))
+		}
+		ntp, err1 := strconv.ParseInt(fields[0], 10, 64)
+		offset, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("%s:%d: '%s' is invalid leap second.", path, n, strings.TrimSpace(line))
+		}
+		start := time.Unix(ntp-ntpEpochOffset, 0).UTC()
+		if len(table) > 0 && start.After(table[len(table)-1].start) == false {
+			return fmt.Errorf("%s:%d: leap seconds must be in order.", path, n)
+		}
+		table = append(table, leapSecond{start: start, offset: offset})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(table) == 0 {
+		return fmt.Errorf("%s: no leap seconds.", path)
+	}
+	leapSeconds = table
+	log.Printf("leap seconds: %d entries from %s", len(table), path)
+	return nil
+}
+
+// taiOffset UTC の t における TAI - UTC. 表より前は最初の値を使います.
+func taiOffset(t time.Time) time.Duration {
+	offset := leapSeconds[0].offset
+	for _, l := range leapSeconds {
+		if t.Before(l.start) {
+			break
+		}
+		offset = l.offset
+	}
+	return time.Duration(offset) * time.Second
+}
+
+// behindTAI scale の時刻が TAI より遅れている時間
+func behindTAI(scale TimeScale) time.Duration {
+	if scale == ScaleGPS {
+		return gpsOffset
+	}
+	return 0
+}
+
+// toScale UTC の t を scale の時刻にする. leap のときは t の 1 秒後のうるう秒です.
+func toScale(t time.Time, leap bool, scale TimeScale) time.Time {
+	if scale == ScaleUTC {
+		return t
+	}
+	result := t.Add(taiOffset(t) - behindTAI(scale))
+	if leap {
+		result = result.Add(time.Second)
+	}
+	return result
+}
+
+// fromScale scale の時刻 t を UTC にする. UTC のうるう秒 (23:59:60) にあたるときは,
+// その 1 秒前の時刻と true を返します.
+func fromScale(t time.Time, scale TimeScale) (time.Time, bool) {
+	if scale == ScaleUTC {
+		return t, false
+	}
+	tai := t.Add(behindTAI(scale))
+	for i := len(leapSeconds) - 1; i >= 0; i-- {
+		offset := time.Duration(leapSeconds[i].offset) * time.Second
+		previous := offset
+		if i > 0 {
+			previous = time.Duration(leapSeconds[i-1].offset) * time.Second
+		}
+		start := leapSeconds[i].start
+		if tai.Before(start.Add(previous)) {
+			continue
+		}
+		// 直前の差との間はうるう秒
+		if d := tai.Sub(start.Add(previous)); d < offset-previous {
+			return start.Add(-time.Second + d%time.Second).In(t.Location()), true
+		}
+		return tai.Add(-offset), false
+	}
+	return tai.Add(-time.Duration(leapSeconds[0].offset) * time.Second), false
+}
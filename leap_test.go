@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFromScale(t *testing.T) {
+	params := []struct {
+		input  string
+		scale  TimeScale
+		expect string
+		leap   bool
+	}{
+		{input: "2024-01-01T00:00:00Z", scale: ScaleUTC, expect: "2024-01-01T00:00:00Z"},
+		{input: "2024-01-01T00:00:37Z", scale: ScaleTAI, expect: "2024-01-01T00:00:00Z"},
+		{input: "2024-01-01T00:00:18Z", scale: ScaleGPS, expect: "2024-01-01T00:00:00Z"},
+		// 2016-12-31 23:59:60 UTC のうるう秒
+		{input: "2017-01-01T00:00:35Z", scale: ScaleTAI, expect: "2016-12-31T23:59:59Z"},
+		{input: "2017-01-01T00:00:36Z", scale: ScaleTAI, expect: "2016-12-31T23:59:59Z", leap: true},
+		{input: "2017-01-01T00:00:36.5Z", scale: ScaleTAI, expect: "2016-12-31T23:59:59.5Z", leap: true},
+		{input: "2017-01-01T00:00:37Z", scale: ScaleTAI, expect: "2017-01-01T00:00:00Z"},
+		{input: "1970-01-01T00:00:10Z", scale: ScaleTAI, expect: "1970-01-01T00:00:00Z"},
+	}
+
+	for _, p := range params {
+		input, _ := time.Parse(time.RFC3339Nano, p.input)
+		actual, leap := fromScale(input, p.scale)
+		if actual.UTC().Format(time.RFC3339Nano) != p.expect || leap != p.leap {
+			t.Errorf("fromScale(%s, %d) = %s, %v; want %s, %v", p.input, p.scale, actual.UTC().Format(time.RFC3339Nano), leap, p.expect, p.leap)
+		}
+
+		back := toScale(actual, leap, p.scale)
+		if back.Equal(input) == false {
+			t.Errorf("toScale(fromScale(%s, %d)) = %s", p.input, p.scale, back.UTC().Format(time.RFC3339Nano))
+		}
+	}
+}
+
+func TestLoadLeapSeconds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leap-seconds.list")
+	content := "#@\t3960057600\n2272060800\t10\t# 1 Jan 1972\n\n3692217600\t37\t# 1 Jan 2017\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { leapSeconds = builtinLeapSeconds }()
+
+	if err := loadLeapSeconds(path); err != nil {
+		t.Fatalf("loadLeapSeconds() = %v", err)
+	}
+	if len(leapSeconds) != 2 {
+		t.Fatalf("loadLeapSeconds() = %d entries; want 2", len(leapSeconds))
+	}
+	if s := leapSeconds[1].start.Format(time.RFC3339); s != "2017-01-01T00:00:00Z" {
+		t.Errorf("leapSeconds[1].start = %s; want 2017-01-01T00:00:00Z", s)
+	}
+	d := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	if offset := taiOffset(d); offset != 10*time.Second {
+		t.Errorf("taiOffset(%v) = %v; want 10s", d, offset)
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid")
+	if err := ioutil.WriteFile(invalid, []byte("2272060800\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadLeapSeconds(invalid); err == nil {
+		t.Errorf("loadLeapSeconds(%q) = nil; want error", invalid)
+	}
+	missing := filepath.Join(t.TempDir(), "missing")
+	if err := loadLeapSeconds(missing); errors.Is(err, fs.ErrNotExist) == false {
+		t.Errorf("loadLeapSeconds(%q) = %v; want %v", missing, err, fs.ErrNotExist)
+	}
+}
+
+func TestFormatLeapSecond(t *testing.T) {
+	// 2016-12-31 23:59:60 UTC のうるう秒は 23:59:59 の時刻で表す
+	at := time.Date(2016, 12, 31, 23, 59, 59, 0, time.UTC)
+	params := []struct {
+		layout string
+		expect string
+	}{
+		{layout: time.RFC3339, expect: "2016-12-31T23:59:60Z"},
+		{layout: "15:04:5", expect: "23:59:60"},
+		{layout: "15:04:05.000", expect: "23:59:60.000"},
+		// 分の 59 は置き換えない
+		{layout: "150405", expect: "235960"},
+		{layout: "04 05", expect: "59 60"},
+		{layout: "15:04", expect: "23:59"},
+	}
+	for _, p := range params {
+		if actual := formatLeapSecond(at, p.layout); actual != p.expect {
+			t.Errorf("formatLeapSecond(%q) = %q; want %q", p.layout, actual, p.expect)
+		}
+	}
+}
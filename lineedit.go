@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+// errInterrupted 行の入力中に Ctrl-C が押された
+var errInterrupted = errors.New("interrupted")
+
+// lineReader プロンプトを表示して 1 行読む. 入力の終わりでは io.EOF を返します.
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+// newLineReader 端末のときは行編集と履歴をもつ lineReader を返す.
+// 戻り値の関数で端末の状態を元に戻します.
+func newLineReader(in io.Reader, out io.Writer, history func() []string) (lineReader, func()) {
+	if f, ok := in.(*os.File); ok {
+		if restore, err := makeRaw(f); err == nil {
+			return &termReader{in: bufio.NewReader(f), out: out, history: history}, restore
+		}
+	}
+	return &plainReader{scanner: bufio.NewScanner(in), out: out}, func() {}
+}
+
+type plainReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *plainReader) readLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if r.scanner.Scan() == false {
+		fmt.Fprintln(r.out)
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+// termReader 端末用の lineReader. カーソル移動, 削除, 履歴の呼び出しができます.
+type termReader struct {
+	in      *bufio.Reader
+	out     io.Writer
+	history func() []string
+}
+
+const (
+	keyCtrlA     = 1
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlK     = 11
+	keyCtrlU     = 21
+	keyBackspace = 127
+	keyCtrlH     = 8
+	keyEscape    = 27
+)
+
+func (r *termReader) readLine(prompt string) (string, error) {
+	var buf []rune
+	pos := 0
+	history := r.history()
+	index := len(history)
+
+	redraw := func() {
+		// 行頭に戻って書き直し, カーソルを pos の位置に移動する
+		fmt.Fprintf(r.out, "\r%s%s\x1b[K\r", prompt, string(buf))
+		if n := utf8.RuneCountInString(prompt) + pos; n > 0 {
+			fmt.Fprintf(r.out, "\x1b[%dC", n)
+		}
+	}
+	redraw()
+
+	for {
+		c, _, err := r.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch c {
+		case '\r', '\n':
+			fmt.Fprint(r.out, "\r\n")
+			return string(buf), nil
+		case keyCtrlC:
+			fmt.Fprint(r.out, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(buf) == 0 {
+				fmt.Fprint(r.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+			}
+		case keyBackspace, keyCtrlH:
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+		case keyCtrlA:
+			pos = 0
+		case keyCtrlE:
+			pos = len(buf)
+		case keyCtrlK:
+			buf = buf[:pos]
+		case keyCtrlU:
+			buf = buf[pos:]
+			pos = 0
+		case keyEscape:
+			switch r.escape() {
+			case 'A':
+				if index > 0 {
+					index--
+					buf = []rune(history[index])
+					pos = len(buf)
+				}
+			case 'B':
+				if index < len(history) {
+					index++
+					buf = nil
+					if index < len(history) {
+						buf = []rune(history[index])
+					}
+					pos = len(buf)
+				}
+			case 'C':
+				if pos < len(buf) {
+					pos++
+				}
+			case 'D':
+				if pos > 0 {
+					pos--
+				}
+			case 'H':
+				pos = 0
+			case 'F':
+				pos = len(buf)
+			case '~':
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+				}
+			}
+		default:
+			if c >= ' ' {
+				buf = append(buf[:pos], append([]rune{c}, buf[pos:]...)...)
+				pos++
+			}
+		}
+		redraw()
+	}
+}
+
+// escape エスケープシーケンスを読み, 最後の文字を返す. ESC [ 3 ~ (Delete) は '~' になります.
+func (r *termReader) escape() rune {
+	c, _, err := r.in.ReadRune()
+	if err != nil || (c != '[' && c != 'O') {
+		return 0
+	}
+	for {
+		c, _, err = r.in.ReadRune()
+		if err != nil {
+			return 0
+		}
+		if c < '0' || c > '9' {
+			return c
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+func meetCommand() cli.Command {
+	return cli.Command{
+		Name:      "meet",
+		Usage:     "全員の就業時間内の会議の時間を探します",
+		UsageText: AppName + " meet --zones zones [options]",
+		Description: `--zones の最初のタイムゾーンの --date の 1 日から, すべてのタイムゾーンで就業時間内に収まる
+   --duration の時間を --step ごとに探し, それぞれのタイムゾーンの時刻で表示します.
+   夏時間はその日の時差で計算し, 週末 (--weekend) と, 設定ファイルの [holidays] でタイムゾーンに指定した祝日は除きます.`,
+		HideHelp: true,
+		Flags: commandFlags(
+			cli.StringFlag{
+				Name:  "date",
+				Usage: "会議の日付を指定します (デフォルトは今日)",
+			},
+			cli.StringFlag{
+				Name:  "duration",
+				Value: "1h",
+				Usage: "会議の時間を指定します",
+			},
+			cli.StringFlag{
+				Name:  "step",
+				Value: "30m",
+				Usage: "会議の開始時刻の間隔を指定します",
+			},
+		),
+		Action: commandAction(meet),
+	}
+}
+
+// participant 会議の参加者のタイムゾーンと祝日
+type participant struct {
+	zoneEntry
+	holidays map[string]string
+}
+
+// available t から end までがすべて就業時間内で, 祝日でないときは true を返す. 週末は就業時間外です.
+// 夏時間の切り替えをまたいでもその地域の時刻で判断するように 1 分ごとに調べます.
+func (p participant) available(t, end time.Time) bool {
+	for ; t.Before(end); t = t.Add(time.Minute) {
+		local := t.In(p.loc)
+		if _, ok := p.holidays[local.Format("2006-01-02")]; ok {
+			return false
+		}
+		if p.hours.contains(local) == false {
+			return false
+		}
+	}
+	return true
+}
+
+func meet(c *cli.Context) error {
+	if len(worldZones) == 0 {
+		return errors.New("meet needs --zones.")
+	}
+	duration, err := parsePositiveDuration(c.String("duration"))
+	if err != nil {
+		return err
+	}
+	step, err := parsePositiveDuration(c.String("step"))
+	if err != nil {
+		return err
+	}
+
+	date := now()
+	if s := c.String("date"); s != "" {
+		dt, err := processFirst(s)
+		if err != nil {
+			return err
+		}
+		date = dt.time
+	}
+
+	participants := make([]participant, len(worldZones))
+	for i, e := range worldZones {
+		days, err := loadZoneHolidays(e.name)
+		if err != nil {
+			return err
+		}
+		participants[i] = participant{zoneEntry: e, holidays: days}
+	}
+
+	// 最初のタイムゾーンのその日の 0 時から翌日の 0 時まで
+	y, m, d := date.Date()
+	start, err := localDate(y, m, d, 0, 0, 0, 0, participants[0].loc)
+	if err != nil {
+		return err
+	}
+	end, err := localDate(y, m, d+1, 0, 0, 0, 0, participants[0].loc)
+	if err != nil {
+		return err
+	}
+
+	var slots []time.Time
+	for t := start; t.Add(duration).After(end) == false; t = t.Add(step) {
+		ok := true
+		for _, p := range participants {
+			if p.available(t, t.Add(duration)) == false {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			slots = append(slots, t)
+		}
+	}
+
+	if len(slots) > 0 {
+		s, err := meetTable(slots, duration, participants, c.String("o"))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(clo.outStream, s)
+	}
+	notes := meetHolidays(start, end, participants)
+	if len(notes) > 0 {
+		if len(slots) > 0 {
+			fmt.Fprintln(clo.outStream)
+		}
+		for _, n := range notes {
+			fmt.Fprintln(clo.outStream, n)
+		}
+	}
+	if len(slots) == 0 {
+		return evalError(fmt.Errorf("no slots on %s.", start.Format("2006-01-02")))
+	}
+	return nil
+}
+
+// meetTable 会議の時間をタイムゾーンごとの列にした表にする. 出力フォーマットを指定しないときは
+// 開始と終了の時刻と略称で, 最初のタイムゾーンと日付が異なるときは +1d のように日数の差を表示します.
+func meetTable(slots []time.Time, duration time.Duration, participants []participant, outputFormat string) (string, error) {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	names := make([]string, len(participants))
+	for i, p := range participants {
+		names[i] = p.name
+	}
+	fmt.Fprintln(w, strings.Join(names, "\t"))
+
+	for _, slot := range slots {
+		first := slot.In(participants[0].loc)
+		cells := make([]string, len(participants))
+		for i, p := range participants {
+			start, end := slot.In(p.loc), slot.Add(duration).In(p.loc)
+			if outputFormat != "" {
+				from, err := formatZoned(&Dt{time: start, format: defaultFormat}, outputFormat, p.loc)
+				if err != nil {
+					return "", err
+				}
+				to, err := formatZoned(&Dt{time: end, format: defaultFormat}, outputFormat, p.loc)
+				if err != nil {
+					return "", err
+				}
+				cells[i] = from + " - " + to
+				continue
+			}
+			cells[i] = start.Format("15:04") + "-" + end.Format("15:04 MST")
+			if d := calendarDays(first, start); d != 0 {
+				cells[i] += fmt.Sprintf(" %+dd", d)
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	w.Flush()
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// meetHolidays start から end までに参加者のタイムゾーンで祝日にあたる日を "* zone 日付 名前" の形式で返す.
+func meetHolidays(start, end time.Time, participants []participant) []string {
+	var notes []string
+	for _, p := range participants {
+		seen := map[string]bool{}
+		var dates []string
+		for t := start; t.Before(end); t = t.Add(time.Hour) {
+			date := t.In(p.loc).Format("2006-01-02")
+			if _, ok := p.holidays[date]; ok && seen[date] == false {
+				seen[date] = true
+				dates = append(dates, date)
+			}
+		}
+		sort.Strings(dates)
+		for _, date := range dates {
+			notes = append(notes, strings.TrimSpace(fmt.Sprintf("* %s %s %s", p.name, date, p.holidays[date])))
+		}
+	}
+	return notes
+}
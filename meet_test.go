@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParticipant_available(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+	cairo, _ := time.LoadLocation("Africa/Cairo")
+	hours, _ := parseWorkingHours("01:00-03:00")
+	night, _ := parseWorkingHours("23:00-02:00")
+	night.weekend, _ = parseWeekend("fri,sat")
+	p := participant{zoneEntry: zoneEntry{name: "America/New_York", loc: newYork, hours: hours}, holidays: map[string]string{"2024-07-04": "Independence Day"}}
+	q := participant{zoneEntry: zoneEntry{name: "Africa/Cairo", loc: cairo, hours: night}}
+	r := p
+	r.hours.weekend = 0
+
+	params := []struct {
+		p        participant
+		start    time.Time
+		duration time.Duration
+		expect   bool
+	}{
+		{p: p, start: time.Date(2024, 1, 15, 6, 0, 0, 0, time.UTC), duration: 2 * time.Hour, expect: true},
+		{p: p, start: time.Date(2024, 1, 15, 6, 0, 0, 0, time.UTC), duration: 2*time.Hour + time.Minute, expect: false},
+		{p: p, start: time.Date(2024, 7, 4, 5, 0, 0, 0, time.UTC), duration: time.Hour, expect: false},
+		// 土曜日と日曜日は就業時間外
+		{p: p, start: time.Date(2024, 1, 13, 6, 0, 0, 0, time.UTC), duration: time.Hour, expect: false},
+		// 夏時間の開始日は 2 時から 3 時がないので 1 時から 3 時は 1 時間
+		{p: r, start: time.Date(2024, 3, 10, 6, 0, 0, 0, time.UTC), duration: time.Hour, expect: true},
+		{p: r, start: time.Date(2024, 3, 10, 6, 0, 0, 0, time.UTC), duration: 2 * time.Hour, expect: false},
+		// 夏時間の開始日は 0 時から 1 時がないので 23 時から 2 時は 2 時間
+		{p: q, start: time.Date(2023, 4, 27, 21, 0, 0, 0, time.UTC), duration: 2 * time.Hour, expect: true},
+		{p: q, start: time.Date(2023, 4, 27, 21, 0, 0, 0, time.UTC), duration: 2*time.Hour + time.Minute, expect: false},
+		// 金曜日と土曜日が週末のときは, 金曜日の夜からの就業時間は就業時間外で, 日曜日の夜からは就業時間内
+		{p: q, start: time.Date(2023, 4, 28, 21, 0, 0, 0, time.UTC), duration: time.Hour, expect: false},
+		{p: q, start: time.Date(2023, 4, 30, 21, 0, 0, 0, time.UTC), duration: time.Hour, expect: true},
+	}
+	for _, param := range params {
+		if actual := param.p.available(param.start, param.start.Add(param.duration)); actual != param.expect {
+			t.Errorf("participant.available(%v, %v) = %v; want %v", param.start, param.duration, actual, param.expect)
+		}
+	}
+}
+
+func TestRun_meet(t *testing.T) {
+	nowInterface = &MyTime{}
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if err := os.Mkdir(filepath.Join(dir, "dt"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		".dt":         "[holidays]\n\"Europe/London\" = \"holidays-uk\"\n",
+		"holidays-uk": "2024-05-06 Early May bank holiday\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, "dt", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	params := []struct {
+		args   []string
+		expect string
+	}{
+		{
+			args: []string{AppName, "meet", "--zones", "Asia/Tokyo,Europe/Berlin", "--date", "2024-01-15"},
+			expect: "Asia/Tokyo       Europe/Berlin\n" +
+				"17:00-18:00 JST  09:00-10:00 CET\n",
+		},
+		// 米国だけ夏時間なので時差が 4 時間
+		{
+			args: []string{AppName, "meet", "--zones", "America/New_York@08:00-17:00,Europe/London", "--date", "2024-03-12", "--duration", "PT2H", "--step", "1h"},
+			expect: "America/New_York  Europe/London\n" +
+				"08:00-10:00 EDT   12:00-14:00 GMT\n" +
+				"09:00-11:00 EDT   13:00-15:00 GMT\n" +
+				"10:00-12:00 EDT   14:00-16:00 GMT\n" +
+				"11:00-13:00 EDT   15:00-17:00 GMT\n" +
+				"12:00-14:00 EDT   16:00-18:00 GMT\n",
+		},
+		// 週末がないときは日曜日も就業時間内
+		{
+			args: []string{AppName, "meet", "--zones", "Asia/Tokyo,America/Los_Angeles", "--weekend", "none", "--date", "2024-01-15", "--step", "1h", "-o", "Mon 15:04"},
+			expect: "Asia/Tokyo             America/Los_Angeles\n" +
+				"Mon 09:00 - Mon 10:00  Sun 16:00 - Sun 17:00\n" +
+				"Mon 10:00 - Mon 11:00  Sun 17:00 - Sun 18:00\n",
+		},
+		{
+			args: []string{AppName, "meet", "--zones", "Asia/Riyadh,Africa/Cairo", "--weekend", "fri,sat", "--date", "2024-01-14", "--step", "2h", "-o", "Mon 15:04"},
+			expect: "Asia/Riyadh            Africa/Cairo\n" +
+				"Sun 10:00 - Sun 11:00  Sun 09:00 - Sun 10:00\n" +
+				"Sun 12:00 - Sun 13:00  Sun 11:00 - Sun 12:00\n" +
+				"Sun 14:00 - Sun 15:00  Sun 13:00 - Sun 14:00\n" +
+				"Sun 16:00 - Sun 17:00  Sun 15:00 - Sun 16:00\n",
+		},
+	}
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+		if status := clo.Run(p.args); status != ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != p.expect {
+			t.Errorf("Run(%s): Output = %q; want %q", p.args, outStream.String(), p.expect)
+		}
+	}
+
+	errors := []struct {
+		args   []string
+		output string
+		expect string
+	}{
+		{args: []string{AppName, "meet", "--zones", "Asia/Tokyo,Europe/London", "--date", "2024-05-06"}, output: "* Europe/London 2024-05-06 Early May bank holiday\n", expect: "no slots on 2024-05-06."},
+		// 日曜日は就業時間外
+		{args: []string{AppName, "meet", "--zones", "Europe/London,America/New_York", "--date", "2024/03/10", "--duration", "1h"}, expect: "no slots on 2024-03-10."},
+		{args: []string{AppName, "meet", "--zones", "Asia/Tokyo,Asia/Dubai@@fri-sat", "--date", "2024-01-19"}, expect: "no slots on 2024-01-19."},
+		{args: []string{AppName, "meet", "--date", "2024-01-15"}, expect: "meet needs --zones."},
+		{args: []string{AppName, "meet", "--zones", "UTC", "--duration", "1M"}, expect: "'1M' is invalid duration."},
+		{args: []string{AppName, "meet", "--zones", "UTC", "--step", "-30m"}, expect: "'-30m' is invalid duration."},
+	}
+	for _, p := range errors {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+		if status := clo.Run(p.args); status == ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want error", p.args, status)
+		}
+		if outStream.String() != p.output {
+			t.Errorf("Run(%s): Output = %q; want %q", p.args, outStream.String(), p.output)
+		}
+		if strings.Contains(errStream.String(), p.expect) == false {
+			t.Errorf("Run(%s): Error = %v; want %v", p.args, errStream.String(), p.expect)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// weekStart 週の始まりの曜日
+var weekStart = time.Monday
+
+var clockUnits = map[byte]time.Duration{
+	'h': time.Hour,
+	'm': time.Minute,
+	's': time.Second,
+}
+
+// truncate t をその期間の始まりに切り捨てる. unit は YMWDhms のいずれか.
+func truncate(t time.Time, unit byte) (time.Time, error) {
+	return truncateBy(t, 1, unit)
+}
+
+// truncateBy t を n 単位ごとの区切りに切り捨てる.
+// 年月は暦の, 日以下はその日の 0 時からの区切りになります. 区切りの始まりが夏時間の切り替えで
+// 存在しないときや 2 回あるときは dstPolicy に従います.
+func truncateBy(t time.Time, n int, unit byte) (time.Time, error) {
+	if n < 1 {
+		return t, fmt.Errorf("'%d' is invalid period.", n)
+	}
+
+	y, m, d := t.Date()
+	loc := t.Location()
+	switch unit {
+	case 'Y':
+		return localDate(y-y%n, time.January, 1, 0, 0, 0, 0, loc)
+	case 'M':
+		months := int(m) - 1
+		return localDate(y, time.Month(months-months%n+1), 1, 0, 0, 0, 0, loc)
+	case 'W':
+		offset := (int(t.Weekday()) - int(weekStart) + 7) % 7
+		if n > 1 {
+			// n 週ごとの区切りは ISO 週番号を基準にする
+			_, week := time.Date(y, m, d-offset, 0, 0, 0, 0, time.UTC).ISOWeek()
+			offset += 7 * ((week - 1) % n)
+		}
+		return localDate(y, m, d-offset, 0, 0, 0, 0, loc)
+	case 'D':
+		return localDate(y, m, d-(d-1)%n, 0, 0, 0, 0, loc)
+	case 'h', 'm', 's':
+		size := time.Duration(n) * clockUnits[unit]
+		midnight, err := localDate(y, m, d, 0, 0, 0, 0, loc)
+		if err != nil {
+			return t, err
+		}
+		return midnight.Add(t.Sub(midnight) / size * size), nil
+	default:
+		return t, fmt.Errorf("'%c' is invalid unit.", unit)
+	}
+}
+
+// StartOf その期間の始まりに丸める.
+func (dt *Dt) StartOf(unit byte) (*Dt, error) {
+	t, err := truncate(dt.time, unit)
+	if err != nil {
+		return dt, err
+	}
+	return &Dt{time: t, format: dt.format}, nil
+}
+
+// EndOf その期間の終わり (次の期間の始まりの 1 秒前) に丸める.
+func (dt *Dt) EndOf(unit byte) (*Dt, error) {
+	start, err := dt.StartOf(unit)
+	if err != nil {
+		return dt, err
+	}
+
+	var next *Dt
+	switch unit {
+	case 'Y':
+		next, err = start.AddYear(1, Normalize)
+	case 'M':
+		next, err = start.AddMonth(1, Normalize)
+	case 'W':
+		next, err = start.AddDay(7)
+	case 'D':
+		next, err = start.AddDay(1)
+	case 'h':
+		next = start.AddHour(1)
+	case 'm':
+		next = start.AddMinute(1)
+	default:
+		next = start.AddSecond(1)
+	}
+	if err != nil {
+		return dt, err
+	}
+	return next.AddSecond(-1), nil
+}
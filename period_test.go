@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTruncateBy(t *testing.T) {
+	tm := time.Date(2018, 5, 12, 17, 34, 56, 0, time.Local)
+	params := []struct {
+		n      int
+		unit   byte
+		expect time.Time
+	}{
+		{n: 1, unit: 'Y', expect: time.Date(2018, 1, 1, 0, 0, 0, 0, time.Local)},
+		{n: 10, unit: 'Y', expect: time.Date(2010, 1, 1, 0, 0, 0, 0, time.Local)},
+		{n: 1, unit: 'M', expect: time.Date(2018, 5, 1, 0, 0, 0, 0, time.Local)},
+		{n: 3, unit: 'M', expect: time.Date(2018, 4, 1, 0, 0, 0, 0, time.Local)},
+		{n: 1, unit: 'W', expect: time.Date(2018, 5, 7, 0, 0, 0, 0, time.Local)},
+		{n: 1, unit: 'D', expect: time.Date(2018, 5, 12, 0, 0, 0, 0, time.Local)},
+		{n: 1, unit: 'h', expect: time.Date(2018, 5, 12, 17, 0, 0, 0, time.Local)},
+		{n: 6, unit: 'h', expect: time.Date(2018, 5, 12, 12, 0, 0, 0, time.Local)},
+		{n: 5, unit: 'm', expect: time.Date(2018, 5, 12, 17, 30, 0, 0, time.Local)},
+		{n: 15, unit: 's', expect: time.Date(2018, 5, 12, 17, 34, 45, 0, time.Local)},
+	}
+
+	for _, p := range params {
+		actual, err := truncateBy(tm, p.n, p.unit)
+		if err != nil || actual != p.expect {
+			t.Errorf("truncateBy(%v, %d, %c) = %v, %v; want %v", tm, p.n, p.unit, actual, err, p.expect)
+		}
+	}
+}
+
+func TestTruncateBy_invalid(t *testing.T) {
+	if _, err := truncateBy(time.Now(), 0, 'D'); err == nil {
+		t.Errorf("truncateBy(0) error = nil; want error")
+	}
+	if _, err := truncateBy(time.Now(), 1, 'x'); err == nil {
+		t.Errorf("truncateBy(x) error = nil; want error")
+	}
+}
+
+func TestDt_EndOf(t *testing.T) {
+	dt := &Dt{time: time.Date(2024, 2, 10, 17, 34, 56, 0, time.Local)}
+	params := []struct {
+		unit   byte
+		expect time.Time
+	}{
+		{unit: 'Y', expect: time.Date(2024, 12, 31, 23, 59, 59, 0, time.Local)},
+		{unit: 'M', expect: time.Date(2024, 2, 29, 23, 59, 59, 0, time.Local)},
+		{unit: 'W', expect: time.Date(2024, 2, 11, 23, 59, 59, 0, time.Local)},
+		{unit: 'D', expect: time.Date(2024, 2, 10, 23, 59, 59, 0, time.Local)},
+		{unit: 'h', expect: time.Date(2024, 2, 10, 17, 59, 59, 0, time.Local)},
+	}
+
+	for _, p := range params {
+		actual, err := dt.EndOf(p.unit)
+		if err != nil || actual.get() != p.expect {
+			t.Errorf("Dt.EndOf(%c) = %v, %v; want %v", p.unit, actual.get(), err, p.expect)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const relativeFormat = "relative"
+
+// Rounding 相対表記で最小単位に満たない端数の扱い
+type Rounding int
+
+const (
+	// Floor 端数を切り捨てます
+	Floor Rounding = iota
+	// Round 端数を四捨五入します
+	Round
+	// Ceil 端数を切り上げます
+	Ceil
+)
+
+type relativeUnit struct {
+	duration time.Duration
+	en       string
+	ja       string
+}
+
+var relativeUnits = []relativeUnit{
+	{duration: 365 * 24 * time.Hour, en: "year", ja: "年"},
+	{duration: 30 * 24 * time.Hour, en: "month", ja: "ヶ月"},
+	{duration: 7 * 24 * time.Hour, en: "week", ja: "週間"},
+	{duration: 24 * time.Hour, en: "day", ja: "日"},
+	{duration: time.Hour, en: "hour", ja: "時間"},
+	{duration: time.Minute, en: "minute", ja: "分"},
+	{duration: time.Second, en: "second", ja: "秒"},
+}
+
+// RelativeOption 相対表記のオプション
+type RelativeOption struct {
+	// Granularity 表示する単位の数
+	Granularity int
+	Rounding    Rounding
+	// Lang 表示言語. en または ja
+	Lang string
+}
+
+func parseRounding(s string) (Rounding, error) {
+	switch s {
+	case "", "floor":
+		return Floor, nil
+	case "round":
+		return Round, nil
+	case "ceil":
+		return Ceil, nil
+	default:
+		return Floor, fmt.Errorf("'%s' is invalid rounding.", s)
+	}
+}
+
+// Humanize t が ref からどれだけ離れているかを "3 days ago" や "3日前" のように表す.
+func Humanize(t, ref time.Time, opt RelativeOption) (string, error) {
+	if opt.Lang != "en" && opt.Lang != "ja" {
+		return "", fmt.Errorf("'%s' is invalid language.", opt.Lang)
+	}
+	granularity := opt.Granularity
+	if granularity < 1 {
+		granularity = 1
+	}
+
+	d := t.Sub(ref)
+	future := d > 0
+	if d < 0 {
+		d = -d
+	}
+
+	top := largestUnit(d)
+	smallest := top + granularity - 1
+	if smallest >= len(relativeUnits) {
+		smallest = len(relativeUnits) - 1
+	}
+	d = roundDuration(d, relativeUnits[smallest].duration, opt.Rounding)
+	if d == 0 {
+		if opt.Lang == "ja" {
+			return "たった今", nil
+		}
+		return "now", nil
+	}
+
+	// 丸めによって繰り上がることがあるので最大の単位を求め直す
+	top = largestUnit(d)
+	var parts []string
+	for i := top; i < top+granularity && i < len(relativeUnits); i++ {
+		u := relativeUnits[i]
+		n := int64(d / u.duration)
+		d -= time.Duration(n) * u.duration
+		if n == 0 {
+			continue
+		}
+		parts = append(parts, formatRelativeUnit(n, u, opt.Lang))
+	}
+
+	if opt.Lang == "ja" {
+		if future {
+			return strings.Join(parts, "") + "後", nil
+		}
+		return strings.Join(parts, "") + "前", nil
+	}
+	if future {
+		return "in " + strings.Join(parts, " "), nil
+	}
+	return strings.Join(parts, " ") + " ago", nil
+}
+
+func largestUnit(d time.Duration) int {
+	for i, u := range relativeUnits {
+		if d >= u.duration {
+			return i
+		}
+	}
+	return len(relativeUnits) - 1
+}
+
+func roundDuration(d, unit time.Duration, rounding Rounding) time.Duration {
+	switch rounding {
+	case Round:
+		return (d + unit/2) / unit * unit
+	case Ceil:
+		return (d + unit - 1) / unit * unit
+	default:
+		return d / unit * unit
+	}
+}
+
+func formatRelativeUnit(n int64, u relativeUnit, lang string) string {
+	if lang == "ja" {
+		return fmt.Sprintf("%d%s", n, u.ja)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, u.en)
+	}
+	return fmt.Sprintf("%d %ss", n, u.en)
+}
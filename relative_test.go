@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHumanize(t *testing.T) {
+	ref := time.Date(2018, 5, 12, 17, 30, 0, 0, time.UTC)
+	params := []struct {
+		t      time.Time
+		opt    RelativeOption
+		expect string
+	}{
+		{t: ref, opt: RelativeOption{Lang: "en"}, expect: "now"},
+		{t: ref.AddDate(0, 0, -3), opt: RelativeOption{Lang: "en"}, expect: "3 days ago"},
+		{t: ref.Add(2 * time.Hour), opt: RelativeOption{Lang: "en"}, expect: "in 2 hours"},
+		{t: ref.Add(time.Minute), opt: RelativeOption{Lang: "en"}, expect: "in 1 minute"},
+		{t: ref.AddDate(0, 0, -3), opt: RelativeOption{Lang: "ja"}, expect: "3日前"},
+		{t: ref.Add(2 * time.Hour), opt: RelativeOption{Lang: "ja"}, expect: "2時間後"},
+		{t: ref.Add(-(25*time.Hour + 30*time.Minute)), opt: RelativeOption{Granularity: 2, Lang: "en"}, expect: "1 day 1 hour ago"},
+		{t: ref.Add(-(25*time.Hour + 30*time.Minute)), opt: RelativeOption{Granularity: 3, Lang: "ja"}, expect: "1日1時間30分前"},
+		{t: ref.Add(-(24*time.Hour + 30*time.Minute)), opt: RelativeOption{Granularity: 2, Lang: "en"}, expect: "1 day ago"},
+		{t: ref.Add(90 * time.Minute), opt: RelativeOption{Rounding: Floor, Lang: "en"}, expect: "in 1 hour"},
+		{t: ref.Add(90 * time.Minute), opt: RelativeOption{Rounding: Round, Lang: "en"}, expect: "in 2 hours"},
+		{t: ref.Add(61 * time.Minute), opt: RelativeOption{Rounding: Ceil, Lang: "en"}, expect: "in 2 hours"},
+		{t: ref.Add(59*time.Minute + 40*time.Second), opt: RelativeOption{Rounding: Round, Lang: "en"}, expect: "in 1 hour"},
+		{t: ref.Add(400 * 24 * time.Hour), opt: RelativeOption{Lang: "en"}, expect: "in 1 year"},
+	}
+
+	for _, p := range params {
+		actual, err := Humanize(p.t, ref, p.opt)
+		if err != nil || actual != p.expect {
+			t.Errorf("Humanize(%v, %+v) = %s, %v; want %s", p.t, p.opt, actual, err, p.expect)
+		}
+	}
+}
+
+func TestHumanize_invalidLang(t *testing.T) {
+	now := time.Now()
+	if _, err := Humanize(now, now, RelativeOption{Lang: "fr"}); err == nil {
+		t.Errorf("Humanize() error = nil; want error")
+	}
+}
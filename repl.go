@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+const (
+	replPrompt      = "dt> "
+	historyFileName = "history"
+	maxHistory      = 1000
+)
+
+func replCommand() cli.Command {
+	return cli.Command{
+		Name:      "repl",
+		Usage:     "対話モードで計算します",
+		UsageText: AppName + " repl [options]",
+		Description: `1 行ずつ計算式を評価します. +1D のように演算子で始まる行は直前の結果に続けて計算します.
+   :help でコマンドの一覧を表示します.`,
+		HideHelp: true,
+		Flags:    commandFlags(),
+		Action:   commandAction(repl),
+	}
+}
+
+// replSession 対話モードの状態
+type replSession struct {
+	e           *evaluator
+	format      string
+	saved       map[string]value
+	history     []string
+	historyPath string
+}
+
+func repl(c *cli.Context) error {
+	s := &replSession{
+		e:      newEvaluator(c.String("i"), adjustPolicy(c)),
+		format: c.String("o"),
+		saved:  map[string]value{},
+	}
+	s.e.current = value{dt: &Dt{time: now(), format: defaultFormat}}
+	s.loadHistory()
+
+	reader, restore := newLineReader(clo.inStream, clo.outStream, func() []string { return s.history })
+	defer restore()
+
+	for {
+		line, err := reader.readLine(replPrompt)
+		if err == errInterrupted {
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		line, err = s.expandHistory(strings.TrimSpace(line))
+		if err != nil {
+			fmt.Fprintf(clo.errStream, "%v\n", err)
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		s.addHistory(line)
+
+		quit, err := s.execute(line)
+		if err != nil {
+			fmt.Fprintf(clo.errStream, "%v\n", err)
+		}
+		if quit {
+			break
+		}
+	}
+	s.saveHistory()
+	return nil
+}
+
+// execute 1 行を実行する. 終了するときは true を返す.
+func (s *replSession) execute(line string) (bool, error) {
+	if strings.HasPrefix(line, ":") {
+		return s.command(line)
+	}
+
+	// 演算子や @startM で始まる行は直前の結果に続けて計算する. @1526113800 は日時です
+	var err error
+	switch lex(line)[0].kind {
+	case tokenPlus, tokenMinus, tokenAt:
+		err = s.e.rest(line)
+	default:
+		err = s.e.first(line)
+		if err == nil && s.e.current.dt != nil {
+			fmt.Fprintf(clo.outStream, "# input format: %s\n", s.e.current.dt.format)
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	return false, s.print()
+}
+
+func (s *replSession) print() error {
+	if s.e.current.dt == nil {
+		return outputDurationAs(s.e.current, s.format)
+	}
+	return outputAs(s.e.current.dt, s.format)
+}
+
+func (s *replSession) command(line string) (bool, error) {
+	fields := strings.Fields(line)
+	name, args := fields[0], fields[1:]
+	arg := strings.TrimSpace(strings.TrimPrefix(line, name))
+
+	switch name {
+	case ":q", ":quit", ":exit":
+		return true, nil
+	case ":help", ":h":
+		fmt.Fprint(clo.outStream, replHelp)
+	case ":format", ":f":
+		if arg != "" {
+			s.format = arg
+		}
+		fmt.Fprintf(clo.outStream, "format: %s\n", s.format)
+	case ":tz":
+		if arg != "" {
+			if err := loadZone(arg); err != nil {
+				return false, err
+			}
+		}
+		fmt.Fprintf(clo.outStream, "tz: %s\n", localLocation())
+	case ":adjust":
+		if len(args) > 0 {
+			switch args[0] {
+			case "on":
+				s.e.adjust = AdjustToEndOfMonth
+			case "off":
+				s.e.adjust = Normalize
+			default:
+				return false, fmt.Errorf("'%s' is invalid. use on or off.", args[0])
+			}
+		}
+		fmt.Fprintf(clo.outStream, "adjust: %v\n", s.e.adjust == AdjustToEndOfMonth)
+	case ":save":
+		if len(args) != 1 || identRegexp.MatchString(args[0]) == false {
+			return false, errors.New("usage: :save name")
+		}
+		s.saved[args[0]] = s.e.current
+		// 保存した値は計算式の変数としても使える
+		s.e.vars[args[0]] = s.e.current
+	case ":load":
+		if len(args) != 1 {
+			return false, errors.New("usage: :load name")
+		}
+		v, ok := s.saved[args[0]]
+		if ok == false {
+			return false, fmt.Errorf("'%s' is not saved.", args[0])
+		}
+		s.e.current = v
+		return false, s.print()
+	case ":list":
+		names := make([]string, 0, len(s.saved))
+		for k := range s.saved {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			fmt.Fprintf(clo.outStream, "%s: ", k)
+			v := s.saved[k]
+			if v.dt == nil {
+				outputDurationAs(v, s.format)
+			} else {
+				outputAs(v.dt, s.format)
+			}
+		}
+	case ":history":
+		for i, h := range s.history {
+			fmt.Fprintf(clo.outStream, "%5d  %s\n", i+1, h)
+		}
+	default:
+		return false, fmt.Errorf("'%s' is unknown command. see :help.", name)
+	}
+	return false, nil
+}
+
+const replHelp = `+1D, -1M, @startM  直前の結果に続けて計算します
+date [expr]        新しく計算します
+:format [name]     出力フォーマットを表示・変更します (-o)
+:tz [zone]         タイムゾーンを表示・変更します (--tz)
+:adjust [on|off]   月末日の調整を表示・変更します (-a)
+:save name         直前の結果を保存します. 計算式の変数としても使えます
+:load name         保存した値を直前の結果にします
+:list              保存した値を表示します
+:history           履歴を表示します. !! や !n で履歴を再実行できます
+:quit              終了します
+`
+
+// expandHistory !! と !n を履歴の行に置き換える.
+func (s *replSession) expandHistory(line string) (string, error) {
+	if strings.HasPrefix(line, "!") == false || len(line) < 2 {
+		return line, nil
+	}
+	if line == "!!" {
+		if len(s.history) == 0 {
+			return "", errors.New("history is empty.")
+		}
+		return s.history[len(s.history)-1], nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 || n > len(s.history) {
+		return "", fmt.Errorf("'%s' is not in history.", line)
+	}
+	return s.history[n-1], nil
+}
+
+func (s *replSession) addHistory(line string) {
+	s.history = append(s.history, line)
+	if len(s.history) > maxHistory {
+		s.history = s.history[len(s.history)-maxHistory:]
+	}
+}
+
+func (s *replSession) loadHistory() {
+	dir, err := configDir()
+	if err != nil {
+		return
+	}
+	s.historyPath = filepath.Join(dir, historyFileName)
+
+	f, err := os.Open(s.historyPath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		s.addHistory(scanner.Text())
+	}
+}
+
+func (s *replSession) saveHistory() {
+	if s.historyPath == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.historyPath), 0755); err != nil {
+		return
+	}
+	ioutil.WriteFile(s.historyPath, []byte(strings.Join(s.history, "\n")+"\n"), 0600)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_repl(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	nowInterface = &MyTime{}
+
+	input := strings.Join([]string{
+		"2024-01-31",
+		"+1M",
+		":adjust on",
+		"2024-01-31 +1M",
+		":save x",
+		"-1D",
+		":format def",
+		":load x",
+		"x + 1D",
+		"!!",
+		"@endM",
+		"@1526113800",
+		":bogus",
+		":quit",
+		"2000-01-01",
+	}, "\n")
+	inStream, outStream, errStream := strings.NewReader(input), new(bytes.Buffer), new(bytes.Buffer)
+	clo := &CLO{inStream: inStream, outStream: outStream, errStream: errStream}
+
+	status := clo.Run([]string{AppName, "repl"})
+	if status != ExitCodeOK {
+		t.Fatalf("Run(repl): ExitStatus = %d; want %d", status, ExitCodeOK)
+	}
+
+	expect := strings.Join([]string{
+		"dt> # input format: 2006-01-02",
+		"2024-01-31",
+		"dt> 2024-03-02",
+		"dt> adjust: true",
+		"dt> # input format: 2006-01-02",
+		"2024-02-29",
+		"dt> dt> 2024-02-28",
+		"dt> format: def",
+		"dt> 2024/02/29 00:00:00",
+		"dt> # input format: 2006-01-02",
+		"2024/03/01 00:00:00",
+		"dt> # input format: 2006-01-02",
+		"2024/03/01 00:00:00",
+		"dt> 2024/03/31 23:59:59",
+		"dt> # input format: unix",
+		"2018/05/12 17:30:00",
+		"dt> dt> ",
+	}, "\n")
+	actual := outStream.String()
+	if actual != expect {
+		t.Errorf("Run(repl): Output = %q; want %q", actual, expect)
+	}
+	if strings.Contains(errStream.String(), "':bogus' is unknown command.") == false {
+		t.Errorf("Run(repl): Error = %q; want unknown command", errStream.String())
+	}
+
+	history, err := ioutil.ReadFile(filepath.Join(dir, "dt", historyFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(string(history), "2024-01-31\n+1M\n") == false {
+		t.Errorf("history = %q; want lines of input", history)
+	}
+}
+
+func TestReplSession_expandHistory(t *testing.T) {
+	s := &replSession{history: []string{"now", "+1D"}}
+	params := []struct {
+		input  string
+		expect string
+		ok     bool
+	}{
+		{input: "!!", expect: "+1D", ok: true},
+		{input: "!1", expect: "now", ok: true},
+		{input: "!3", ok: false},
+		{input: "+1D", expect: "+1D", ok: true},
+	}
+
+	for _, p := range params {
+		actual, err := s.expandHistory(p.input)
+		if (err == nil) != p.ok || actual != p.expect {
+			t.Errorf("expandHistory(%s) = %s, %v; want %s", p.input, actual, err, p.expect)
+		}
+	}
+}
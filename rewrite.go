@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/urfave/cli"
+)
+
+func rewriteCommand() cli.Command {
+	return cli.Command{
+		Name:      "rewrite",
+		Usage:     "標準入力の行に含まれる日時を書き換えます",
+		UsageText: AppName + " rewrite [options] [--] [expr [expr ...]]",
+		Description: `-i のフォーマットか --pattern の正規表現に一致する部分を日時として解釈し,
+   計算式, --tz, -o で変換した結果に置き換えて出力します. それ以外の部分はそのまま出力します.
+   --pattern にグループがあるときは最初のグループだけを置き換えます.
+   -1D のように - で始まる計算式は -- のあとに指定します.`,
+		HideHelp: true,
+		Flags: commandFlags(
+			cli.StringFlag{
+				Name:  "pattern, p",
+				Usage: "日時を探す正規表現を指定します",
+			},
+			cli.StringFlag{
+				Name:  "input-tz",
+				Usage: "タイムゾーンのない日時のタイムゾーンを指定します. 省略したときは --tz と同じです",
+			},
+		),
+		Action: commandAction(rewrite),
+	}
+}
+
+// rewriter 行に含まれる日時を書き換える
+type rewriter struct {
+	re           *regexp.Regexp
+	inputFormat  string
+	inputZone    *time.Location
+	exprs        []string
+	adjust       AdjustDay
+	outputFormat string
+
+	// 同じ日時が続くことが多いので直前の変換結果を使い回す
+	lastInput  string
+	lastOutput string
+}
+
+func rewrite(c *cli.Context) error {
+	r := &rewriter{
+		inputFormat:  c.String("i"),
+		exprs:        c.Args(),
+		adjust:       adjustPolicy(c),
+		outputFormat: c.String("o"),
+	}
+
+	var err error
+	switch {
+	case c.String("pattern") != "":
+		r.re, err = regexp.Compile(c.String("pattern"))
+	case r.inputFormat != "":
+		r.re, err = layoutRegexp(r.inputFormat)
+	default:
+		err = errors.New("rewrite needs -i or --pattern.")
+	}
+	if err != nil {
+		return err
+	}
+	if name := c.String("input-tz"); name != "" {
+		r.inputZone, err = time.LoadLocation(name)
+		if err != nil {
+			return fmt.Errorf("'%s' is invalid time zone.", name)
+		}
+	}
+	log.Printf("rewrite pattern: %s", r.re)
+
+	return r.run(clo.inStream, clo.outStream)
+}
+
+// rewriteChunkSize 並列に書き換えるときのひとかたまりの大きさ
+const rewriteChunkSize = 256 * 1024
+
+// run in を読み, 書き換えた行を out に書く.
+// 行のかたまりごとに並列に書き換え, 入力と同じ順番で出力します.
+func (r *rewriter) run(in io.Reader, out io.Writer) error {
+	reader := bufio.NewReaderSize(in, rewriteChunkSize)
+	writer := bufio.NewWriterSize(out, rewriteChunkSize)
+	defer writer.Flush()
+
+	// 待ち合わせる結果の数で同時に書き換えるかたまりの数を制限する
+	results := make(chan chan string, runtime.NumCPU())
+	var readErr error
+	go func() {
+		defer close(results)
+		for {
+			chunk, err := readChunk(reader)
+			if chunk != "" {
+				result := make(chan string, 1)
+				results <- result
+				go func(w rewriter) {
+					result <- w.chunk(chunk)
+				}(*r)
+			}
+			if err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				return
+			}
+		}
+	}()
+
+	var writeErr error
+	for result := range results {
+		s := <-result
+		if writeErr == nil {
+			_, writeErr = writer.WriteString(s)
+		}
+	}
+	if readErr != nil {
+		return readErr
+	}
+	return writeErr
+}
+
+// readChunk rewriteChunkSize 程度の大きさの, 行の途中で切れないかたまりを読む.
+func readChunk(reader *bufio.Reader) (string, error) {
+	buf := make([]byte, rewriteChunkSize)
+	n, err := io.ReadFull(reader, buf)
+	buf = buf[:n]
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	if err != nil || buf[n-1] == '\n' {
+		return string(buf), err
+	}
+	rest, err := reader.ReadString('\n')
+	return string(buf) + rest, err
+}
+
+// chunk 複数の行を書き換える.
+func (r *rewriter) chunk(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for s != "" {
+		i := strings.IndexByte(s, '\n') + 1
+		if i == 0 {
+			i = len(s)
+		}
+		b.WriteString(r.line(s[:i]))
+		s = s[i:]
+	}
+	return b.String()
+}
+
+// line 1 行を書き換える. 日時として解釈できない部分はそのまま残します.
+func (r *rewriter) line(line string) string {
+	matches := r.re.FindAllStringSubmatchIndex(line, -1)
+	if matches == nil {
+		return line
+	}
+
+	var b strings.Builder
+	prev := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		// グループがあるときは最初のグループを置き換える
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		s, ok := r.convert(line[start:end])
+		if ok == false {
+			continue
+		}
+		b.WriteString(line[prev:start])
+		b.WriteString(s)
+		prev = end
+	}
+	b.WriteString(line[prev:])
+	return b.String()
+}
+
+func (r *rewriter) convert(s string) (string, bool) {
+	if s == r.lastInput && r.lastOutput != "" {
+		return r.lastOutput, true
+	}
+
+	dt, err := parseDate(s, r.inputFormat, r.inputZone)
+	if err != nil {
+		log.Printf("rewrite: '%s' is not a date", s)
+		return "", false
+	}
+	if len(r.exprs) > 0 {
+		e := newEvaluator(r.inputFormat, r.adjust)
+		e.zone = r.inputZone
+		e.current = value{dt: dt}
+		for _, expr := range r.exprs {
+			if err := e.rest(expr); err != nil {
+				log.Printf("rewrite: %v", err)
+				return "", false
+			}
+		}
+		if e.current.dt == nil {
+			return "", false
+		}
+		dt = e.current.dt
+	}
+
+	out, err := formatOutput(dt, r.outputFormat)
+	if err != nil {
+		log.Printf("rewrite: %v", err)
+		return "", false
+	}
+	r.lastInput, r.lastOutput = s, out
+	return out, true
+}
+
+// layoutChunks レイアウトの要素と一致する正規表現. 長いものから順に調べます.
+var layoutChunks = []struct {
+	layout string
+	re     string
+}{
+	{"January", `[A-Z][a-z]+`},
+	{"Monday", `[A-Z][a-z]+day`},
+	{"Jan", `[A-Z][a-z]{2}`},
+	{"Mon", `[A-Z][a-z]{2}`},
+	{"MST", `(?:[A-Z]{2,5}|[+-]\d{2,4})`},
+	{"2006", `\d{4}`},
+	{"Z07:00:00", `(?:Z|[+-]\d{2}:\d{2}:\d{2})`},
+	{"-07:00:00", `[+-]\d{2}:\d{2}:\d{2}`},
+	{"Z070000", `(?:Z|[+-]\d{6})`},
+	{"-070000", `[+-]\d{6}`},
+	{"Z07:00", `(?:Z|[+-]\d{2}:\d{2})`},
+	{"-07:00", `[+-]\d{2}:\d{2}`},
+	{"Z0700", `(?:Z|[+-]\d{4})`},
+	{"-0700", `[+-]\d{4}`},
+	{"Z07", `(?:Z|[+-]\d{2})`},
+	{"-07", `[+-]\d{2}`},
+	{"__2", `[ \d]{2}\d`},
+	{"002", `\d{3}`},
+	{"_2", `[ \d]\d`},
+	{"01", `\d{2}`},
+	{"02", `\d{2}`},
+	{"03", `\d{2}`},
+	{"04", `\d{2}`},
+	{"05", `\d{2}`},
+	{"06", `\d{2}`},
+	{"15", `\d{2}`},
+	{"PM", `[AP]M`},
+	{"pm", `[ap]m`},
+	{"1", `\d{1,2}`},
+	{"2", `\d{1,2}`},
+	{"3", `\d{1,2}`},
+	{"4", `\d{1,2}`},
+	{"5", `\d{1,2}`},
+}
+
+var fractionRegexp = regexp.MustCompile(`^[.,](0+|9+)`)
+
+// layoutRegexp Go のレイアウトに一致する文字列を探す正規表現を作る.
+func layoutRegexp(layout string) (*regexp.Regexp, error) {
+	if v, ok := formats[layout]; ok {
+		layout = v
+	}
+	switch layout {
+	case unixSeconds:
+		return regexp.MustCompile(`\b\d{10}\b`), nil
+	case unixMilliSeconds:
+		return regexp.MustCompile(`\b\d{13}\b`), nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(layout); {
+		// 秒の小数部 .000 は桁数どおり, .999 は省略できる
+		if m := fractionRegexp.FindStringSubmatch(layout[i:]); m != nil && (i+len(m[0]) == len(layout) || isDigit(layout[i+len(m[0])]) == false) {
+			if m[1][0] == '0' {
+				fmt.Fprintf(&b, `[.,]\d{%d}`, len(m[1]))
+			} else {
+				b.WriteString(`(?:[.,]\d+)?`)
+			}
+			i += len(m[0])
+			continue
+		}
+
+		matched := false
+		for _, c := range layoutChunks {
+			if strings.HasPrefix(layout[i:], c.layout) {
+				b.WriteString(c.re)
+				i += len(c.layout)
+				matched = true
+				break
+			}
+		}
+		if matched == false {
+			_, size := utf8.DecodeRuneInString(layout[i:])
+			b.WriteString(regexp.QuoteMeta(layout[i : i+size]))
+			i += size
+		}
+	}
+	return regexp.Compile(b.String())
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLayoutRegexp(t *testing.T) {
+	params := []struct {
+		layout string
+		input  string
+		expect []string
+	}{
+		{layout: "2006-01-02 15:04:05", input: "a 2024-01-31 23:59:59 b 2024-02-01 00:00:00", expect: []string{"2024-01-31 23:59:59", "2024-02-01 00:00:00"}},
+		{layout: "RFC3339", input: "t=2024-01-31T23:59:59+09:00 t=2024-01-31T14:59:59Z", expect: []string{"2024-01-31T23:59:59+09:00", "2024-01-31T14:59:59Z"}},
+		{layout: "Jan _2 15:04:05", input: "Feb  1 00:00:00 host sshd", expect: []string{"Feb  1 00:00:00"}},
+		{layout: "02/Jan/2006:15:04:05 -0700", input: `[31/Jan/2024:23:59:59 +0900] "GET /"`, expect: []string{"31/Jan/2024:23:59:59 +0900"}},
+		{layout: "15:04:05.000", input: "12:00:00.123 12:00:00", expect: []string{"12:00:00.123"}},
+		{layout: "15:04:05.999", input: "12:00:00.1 12:00:01", expect: []string{"12:00:00.1", "12:00:01"}},
+		{layout: "2006年1月2日", input: "締切は2024年2月9日です", expect: []string{"2024年2月9日"}},
+		{layout: "unix", input: "ts=1700000000 id=123", expect: []string{"1700000000"}},
+	}
+
+	for _, p := range params {
+		re, err := layoutRegexp(p.layout)
+		if err != nil {
+			t.Fatalf("layoutRegexp(%s) = %v", p.layout, err)
+		}
+		actual := re.FindAllString(p.input, -1)
+		if strings.Join(actual, "|") != strings.Join(p.expect, "|") {
+			t.Errorf("layoutRegexp(%s).FindAllString(%s) = %q; want %q", p.layout, p.input, actual, p.expect)
+		}
+	}
+}
+
+func TestRun_rewrite(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	input := strings.Join([]string{
+		"INFO 2024-01-31 23:30:00 start",
+		"WARN 2024-01-31 23:59:59 slow 2024-02-01 00:00:01 end",
+		"invalid 2024-13-45 00:00:00",
+		"no timestamp",
+		"last line without newline 2024-02-29 12:00:00",
+	}, "\n")
+
+	params := []struct {
+		args   []string
+		expect []string
+	}{
+		{
+			args: []string{"-i", "2006-01-02 15:04:05", "--input-tz", "UTC", "--tz", "Asia/Tokyo", "-o", "RFC3339"},
+			expect: []string{
+				"INFO 2024-02-01T08:30:00+09:00 start",
+				"WARN 2024-02-01T08:59:59+09:00 slow 2024-02-01T09:00:01+09:00 end",
+				"invalid 2024-13-45 00:00:00",
+				"no timestamp",
+				"last line without newline 2024-02-29T21:00:00+09:00",
+			},
+		},
+		{
+			args: []string{"-i", "2006-01-02 15:04:05", "--", "-1D", "+30m"},
+			expect: []string{
+				"INFO 2024-01-31 00:00:00 start",
+				"WARN 2024-01-31 00:29:59 slow 2024-01-31 00:30:01 end",
+				"invalid 2024-13-45 00:00:00",
+				"no timestamp",
+				"last line without newline 2024-02-28 12:30:00",
+			},
+		},
+		{
+			args: []string{"-p", `(\S+ \S+) start`, "-o", "YMD/"},
+			expect: []string{
+				"INFO 2024/01/31 start",
+				"WARN 2024-01-31 23:59:59 slow 2024-02-01 00:00:01 end",
+				"invalid 2024-13-45 00:00:00",
+				"no timestamp",
+				"last line without newline 2024-02-29 12:00:00",
+			},
+		},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := clo.Run(append([]string{AppName, "rewrite"}, p.args...))
+		if status != ExitCodeOK {
+			t.Fatalf("Run(%v): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream)
+		}
+		expect := strings.Join(p.expect, "\n")
+		if outStream.String() != expect {
+			t.Errorf("Run(%v): Output = %q; want %q", p.args, outStream, expect)
+		}
+	}
+}
+
+func TestRewriter_chunk(t *testing.T) {
+	re, _ := layoutRegexp("2006-01-02")
+	r := &rewriter{re: re, inputFormat: "2006-01-02", outputFormat: "2006/01/02"}
+
+	// かたまりの大きさを超える入力でも順番が変わらない
+	var input, expect strings.Builder
+	for i := 0; input.Len() < 3*rewriteChunkSize; i++ {
+		input.WriteString("line 2024-01-31 end\n")
+		expect.WriteString("line 2024/01/31 end\n")
+	}
+	out := new(bytes.Buffer)
+	if err := r.run(strings.NewReader(input.String()), out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != expect.String() {
+		t.Errorf("run(): Output length = %d; want %d", out.Len(), expect.Len())
+	}
+}
+
+func TestRun_rewriteError(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	params := [][]string{
+		{},
+		{"-p", "("},
+		{"-i", "2006-01-02", "--input-tz", "Nowhere/City"},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{inStream: strings.NewReader(""), outStream: outStream, errStream: errStream}
+
+		status := clo.Run(append([]string{AppName, "rewrite"}, p...))
+		if status != ExitCodeError {
+			t.Errorf("Run(%v): ExitStatus = %d; want %d", p, status, ExitCodeError)
+		}
+	}
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// rruleSearchYears 繰り返しを探す年数. これより先は探しません.
+const rruleSearchYears = 400
+
+// rruleDefaultLimit COUNT も UNTIL もないときに出力する数
+const rruleDefaultLimit = 10
+
+var rruleFreqs = map[string]rruleFreq{
+	"YEARLY":   freqYearly,
+	"MONTHLY":  freqMonthly,
+	"WEEKLY":   freqWeekly,
+	"DAILY":    freqDaily,
+	"HOURLY":   freqHourly,
+	"MINUTELY": freqMinutely,
+	"SECONDLY": freqSecondly,
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+var rruleByDayRegexp = regexp.MustCompile(`^([+-]?\d{1,2})?(SU|MO|TU|WE|TH|FR|SA)$`)
+
+func rruleCommand() cli.Command {
+	return cli.Command{
+		Name:      "rrule",
+		Usage:     "iCalendar の RRULE の繰り返しを展開します",
+		UsageText: AppName + " rrule [options] 'RRULE' [date [expr [expr ...]]]",
+		Description: `計算した日時 (デフォルトは現在時刻) を DTSTART として RFC 5545 の RRULE の日時を出力します.
+   FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH, BYSETPOS, WKST を使えます.
+   "RRULE:" や "EXDATE:" で始まる行を改行で区切って指定することもできます.`,
+		HideHelp: true,
+		Flags: commandFlags(
+			cli.StringSliceFlag{
+				Name:  "exdate",
+				Usage: "除く日時を指定します. カンマで区切るか繰り返して複数指定できます",
+			},
+			cli.IntFlag{
+				Name:  "limit, n",
+				Usage: "出力する数の上限を指定します (COUNT も UNTIL もないときのデフォルトは 10)",
+			},
+		),
+		Action: commandAction(rrule),
+	}
+}
+
+// rruleFreq FREQ の繰り返しの単位
+type rruleFreq int
+
+const (
+	freqYearly rruleFreq = iota
+	freqMonthly
+	freqWeekly
+	freqDaily
+	freqHourly
+	freqMinutely
+	freqSecondly
+)
+
+// rruleByDay BYDAY のひとつの要素. n が 0 でないときは第 n (負のときは最後から) の曜日です.
+type rruleByDay struct {
+	n       int
+	weekday time.Weekday
+}
+
+// recurrence RRULE
+type recurrence struct {
+	freq       rruleFreq
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []rruleByDay
+	byMonthDay []int
+	byMonth    []int
+	bySetPos   []int
+	wkst       time.Weekday
+	exdates    []time.Time
+}
+
+func rrule(c *cli.Context) error {
+	if len(c.Args()) == 0 {
+		return errors.New("rrule needs a RRULE.")
+	}
+	v, err := evalArgs(c, c.Args()[1:])
+	if err != nil {
+		return err
+	}
+	if v.dt == nil {
+		return evalError(errors.New("rrule needs a date."))
+	}
+	start := v.dt.time
+
+	r, err := parseRecurrence(c.Args()[0], start.Location())
+	if err != nil {
+		return err
+	}
+	for _, s := range c.StringSlice("exdate") {
+		for _, value := range strings.Split(s, ",") {
+			t, err := parseICalDate(value, start.Location())
+			if err != nil {
+				return err
+			}
+			r.exdates = append(r.exdates, t)
+		}
+	}
+
+	limit := c.Int("limit")
+	if c.IsSet("limit") == false && r.count == 0 && r.until.IsZero() {
+		limit = rruleDefaultLimit
+	}
+	if limit < 0 {
+		return fmt.Errorf("'%d' is invalid limit.", limit)
+	}
+
+	occurrences, err := r.expand(start, limit)
+	if err != nil {
+		return err
+	}
+	if len(occurrences) == 0 {
+		return evalError(fmt.Errorf("'%s' has no occurrences.", c.Args()[0]))
+	}
+	dts := make([]*Dt, len(occurrences))
+	for i, t := range occurrences {
+		dts[i] = &Dt{time: t, format: v.dt.format}
+	}
+	return outputAll(c.Args()[0], dts)
+}
+
+// parseRecurrence "FREQ=MONTHLY;BYDAY=2TU" を解析する. "RRULE:" と "EXDATE:" の行も受け付けます.
+func parseRecurrence(src string, loc *time.Location) (*recurrence, error) {
+	r := &recurrence{interval: 1, wkst: time.Monday}
+	rule := ""
+	for _, line := range strings.Split(strings.TrimSpace(src), "\n") {
+		line = strings.TrimSpace(line)
+		name, value := "RRULE", line
+		if i := strings.Index(line, ":"); i >= 0 {
+			name, value = line[:i], line[i+1:]
+		}
+		params := strings.Split(name, ";")
+		switch strings.ToUpper(params[0]) {
+		case "RRULE":
+			rule = value
+		case "EXDATE":
+			exLoc := loc
+			for _, p := range params[1:] {
+				if kv := strings.SplitN(p, "=", 2); len(kv) == 2 && strings.EqualFold(kv[0], "TZID") {
+					var err error
+					if exLoc, err = loadLocation(kv[1]); err != nil {
+						return nil, err
+					}
+				}
+			}
+			for _, s := range strings.Split(value, ",") {
+				t, err := parseICalDate(s, exLoc)
+				if err != nil {
+					return nil, err
+				}
+				r.exdates = append(r.exdates, t)
+			}
+		case "DTSTART":
+			return nil, errors.New("DTSTART is given as the date.")
+		default:
+			return nil, fmt.Errorf("'%s' is invalid RRULE.", line)
+		}
+	}
+
+	hasFreq := false
+	for _, part := range strings.Split(rule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("'%s' is invalid RRULE.", rule)
+		}
+		key, value := strings.ToUpper(strings.TrimSpace(kv[0])), strings.ToUpper(strings.TrimSpace(kv[1]))
+		var err error
+		switch key {
+		case "FREQ":
+			var ok bool
+			if r.freq, ok = rruleFreqs[value]; ok == false {
+				err = fmt.Errorf("'%s' is invalid FREQ.", value)
+			}
+			hasFreq = true
+		case "INTERVAL":
+			r.interval, err = parseRRuleInt(key, value)
+		case "COUNT":
+			r.count, err = parseRRuleInt(key, value)
+		case "UNTIL":
+			r.until, err = parseICalDate(value, loc)
+			// 日付だけのときはその日を含む
+			if err == nil && len(value) == len("20060102") {
+				r.until = r.until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+		case "BYDAY":
+			for _, s := range strings.Split(value, ",") {
+				m := rruleByDayRegexp.FindStringSubmatch(s)
+				if m == nil {
+					return nil, fmt.Errorf("'%s' is invalid BYDAY.", s)
+				}
+				n, _ := strconv.Atoi(strings.TrimPrefix(m[1], "+"))
+				if n < -53 || n > 53 {
+					return nil, fmt.Errorf("'%s' is invalid BYDAY.", s)
+				}
+				r.byDay = append(r.byDay, rruleByDay{n: n, weekday: rruleWeekdays[m[2]]})
+			}
+		case "BYMONTHDAY":
+			r.byMonthDay, err = parseRRuleInts(key, value, 31)
+		case "BYMONTH":
+			r.byMonth, err = parseRRuleInts(key, value, 12)
+			for _, m := range r.byMonth {
+				if m < 0 {
+					err = fmt.Errorf("'%s' is invalid BYMONTH.", value)
+				}
+			}
+		case "BYSETPOS":
+			r.bySetPos, err = parseRRuleInts(key, value, 366)
+		case "WKST":
+			var ok bool
+			if r.wkst, ok = rruleWeekdays[value]; ok == false {
+				err = fmt.Errorf("'%s' is invalid WKST.", value)
+			}
+		default:
+			err = fmt.Errorf("'%s' is not supported.", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if hasFreq == false {
+		return nil, fmt.Errorf("'%s' needs FREQ.", rule)
+	}
+	if r.count > 0 && r.until.IsZero() == false {
+		return nil, errors.New("COUNT and UNTIL cannot be used together.")
+	}
+	return r, nil
+}
+
+// parseRRuleInt 1 以上の数を読む.
+func parseRRuleInt(key, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("'%s' is invalid %s.", value, key)
+	}
+	return n, nil
+}
+
+// parseRRuleInts カンマ区切りの 1 から max か -max から -1 までの数を読む.
+func parseRRuleInts(key, value string, max int) ([]int, error) {
+	var values []int
+	for _, s := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimPrefix(s, "+"))
+		if err != nil || n == 0 || n < -max || n > max {
+			return nil, fmt.Errorf("'%s' is invalid %s.", s, key)
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+// parseICalDate iCalendar の 20060102T150405Z, 20060102T150405, 20060102 の形式か, 最初の引数と同じ方法で日時を解析する.
+func parseICalDate(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"20060102T150405", "20060102"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	dt, err := processFirst(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return dt.time, nil
+}
+
+// expand start を DTSTART として繰り返しの日時を返す. limit が 0 より大きいときはその数までです.
+// COUNT には EXDATE で除いた日時も数えます.
+func (r *recurrence) expand(start time.Time, limit int) ([]time.Time, error) {
+	r.defaults(start)
+
+	var result []time.Time
+	generated := 0
+	end := start.AddDate(rruleSearchYears, 0, 0)
+	for period := 0; ; period++ {
+		candidates, from, err := r.period(start, period)
+		if err != nil {
+			return nil, err
+		}
+		if from.After(end) {
+			return result, nil
+		}
+		for _, t := range candidates {
+			if t.Before(start) {
+				continue
+			}
+			if r.until.IsZero() == false && t.After(r.until) {
+				return result, nil
+			}
+			generated++
+			if r.excluded(t) == false {
+				result = append(result, t)
+				if limit > 0 && len(result) >= limit {
+					return result, nil
+				}
+			}
+			if r.count > 0 && generated >= r.count {
+				return result, nil
+			}
+		}
+	}
+}
+
+// defaults BYDAY, BYMONTHDAY, BYMONTH がないときは DTSTART の日や曜日を使う.
+func (r *recurrence) defaults(start time.Time) {
+	if len(r.byDay) > 0 || len(r.byMonthDay) > 0 {
+		return
+	}
+	switch r.freq {
+	case freqYearly:
+		if len(r.byMonth) == 0 {
+			r.byMonth = []int{int(start.Month())}
+		}
+		r.byMonthDay = []int{start.Day()}
+	case freqMonthly:
+		r.byMonthDay = []int{start.Day()}
+	case freqWeekly:
+		r.byDay = []rruleByDay{{weekday: start.Weekday()}}
+	}
+}
+
+// period n 番目の期間の日時を古い順に返す. from は期間の始まりです.
+func (r *recurrence) period(start time.Time, n int) ([]time.Time, time.Time, error) {
+	y, m, d := start.Date()
+	step := n * r.interval
+
+	var first, last time.Time
+	switch r.freq {
+	case freqYearly:
+		first = time.Date(y+step, time.January, 1, 0, 0, 0, 0, time.UTC)
+		last = first.AddDate(1, 0, 0)
+	case freqMonthly:
+		first = time.Date(y, m+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		last = first.AddDate(0, 1, 0)
+	case freqWeekly:
+		offset := (int(start.Weekday()) - int(r.wkst) + 7) % 7
+		first = time.Date(y, m, d-offset+7*step, 0, 0, 0, 0, time.UTC)
+		last = first.AddDate(0, 0, 7)
+	case freqDaily:
+		first = time.Date(y, m, d+step, 0, 0, 0, 0, time.UTC)
+		last = first.AddDate(0, 0, 1)
+	default:
+		// 時分秒の単位は経過時間で進める
+		unit := map[rruleFreq]time.Duration{freqHourly: time.Hour, freqMinutely: time.Minute, freqSecondly: time.Second}[r.freq]
+		t := start.Add(unit * time.Duration(step))
+		local := t.In(start.Location())
+		date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+		if r.matchDay(date) == false {
+			return nil, t, nil
+		}
+		return r.setPos([]time.Time{t}), t, nil
+	}
+
+	var result []time.Time
+	for date := first; date.Before(last); date = date.AddDate(0, 0, 1) {
+		if r.matchDay(date) == false {
+			continue
+		}
+		t, err := localDate(date.Year(), date.Month(), date.Day(), start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+		if err != nil {
+			return nil, first, err
+		}
+		result = append(result, t)
+	}
+	from, _ := localDate(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, start.Location())
+	return r.setPos(result), from, nil
+}
+
+// matchDay date の日付が BYMONTH, BYMONTHDAY, BYDAY に一致するときは true を返す.
+func (r *recurrence) matchDay(date time.Time) bool {
+	if len(r.byMonth) > 0 && containsInt(r.byMonth, int(date.Month())) == false {
+		return false
+	}
+	if len(r.byMonthDay) > 0 {
+		last := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		ok := false
+		for _, d := range r.byMonthDay {
+			if d == date.Day() || (d < 0 && last+1+d == date.Day()) {
+				ok = true
+			}
+		}
+		if ok == false {
+			return false
+		}
+	}
+	if len(r.byDay) > 0 {
+		ok := false
+		for _, b := range r.byDay {
+			if b.weekday == date.Weekday() && (b.n == 0 || r.nthWeekday(date) == b.n || r.nthWeekdayFromEnd(date) == b.n) {
+				ok = true
+			}
+		}
+		if ok == false {
+			return false
+		}
+	}
+	return true
+}
+
+// nthWeekday date がその月 (FREQ=YEARLY で BYMONTH がないときはその年) の第何週の曜日か.
+func (r *recurrence) nthWeekday(date time.Time) int {
+	if r.freq == freqYearly && len(r.byMonth) == 0 {
+		return (date.YearDay()-1)/7 + 1
+	}
+	return (date.Day()-1)/7 + 1
+}
+
+// nthWeekdayFromEnd date がその月 (またはその年) の最後から何番目の曜日か. 負の数です.
+func (r *recurrence) nthWeekdayFromEnd(date time.Time) int {
+	if r.freq == freqYearly && len(r.byMonth) == 0 {
+		days := time.Date(date.Year(), time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+		return -((days-date.YearDay())/7 + 1)
+	}
+	last := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return -((last-date.Day())/7 + 1)
+}
+
+// setPos BYSETPOS で期間の中の何番目かを選ぶ. 負の数は最後からです.
+func (r *recurrence) setPos(candidates []time.Time) []time.Time {
+	if len(r.bySetPos) == 0 {
+		return candidates
+	}
+	var result []time.Time
+	for _, pos := range r.bySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(candidates) + pos
+		}
+		if i >= 0 && i < len(candidates) && containsTime(result, candidates[i]) == false {
+			result = append(result, candidates[i])
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+	return result
+}
+
+func (r *recurrence) excluded(t time.Time) bool {
+	return containsTime(r.exdates, t)
+}
+
+func containsInt(values []int, n int) bool {
+	for _, v := range values {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTime(values []time.Time, t time.Time) bool {
+	for _, v := range values {
+		if v.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecurrence_expand(t *testing.T) {
+	start := time.Date(2024, 1, 9, 10, 0, 0, 0, time.UTC)
+	params := []struct {
+		rule   string
+		limit  int
+		expect []string
+	}{
+		{rule: "FREQ=MONTHLY;BYDAY=2TU;COUNT=3", expect: []string{"2024-01-09", "2024-02-13", "2024-03-12"}},
+		{rule: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=3", expect: []string{"2024-01-31", "2024-02-29", "2024-03-29"}},
+		{rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;UNTIL=20240125", expect: []string{"2024-01-09", "2024-01-11", "2024-01-23", "2024-01-25"}},
+		{rule: "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH;COUNT=2", expect: []string{"2024-11-28", "2025-11-27"}},
+		{rule: "FREQ=YEARLY;BYDAY=-1FR;COUNT=2", expect: []string{"2024-12-27", "2025-12-26"}},
+		{rule: "FREQ=YEARLY;COUNT=2", expect: []string{"2024-01-09", "2025-01-09"}},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=2", expect: []string{"2024-01-31", "2024-02-29"}},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=3", expect: []string{"2024-01-31", "2024-03-31", "2024-05-31"}},
+		{rule: "FREQ=DAILY;INTERVAL=10", limit: 3, expect: []string{"2024-01-09", "2024-01-19", "2024-01-29"}},
+		{rule: "FREQ=DAILY;BYDAY=SA,SU", limit: 2, expect: []string{"2024-01-13", "2024-01-14"}},
+		{rule: "RRULE:FREQ=DAILY;COUNT=3\nEXDATE:20240110T100000Z", expect: []string{"2024-01-09", "2024-01-11"}},
+		{rule: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", expect: nil},
+	}
+
+	for _, p := range params {
+		r, err := parseRecurrence(p.rule, time.UTC)
+		if err != nil {
+			t.Fatalf("parseRecurrence(%q) = %v", p.rule, err)
+		}
+		actual, err := r.expand(start, p.limit)
+		if err != nil {
+			t.Fatalf("expand(%q) = %v", p.rule, err)
+		}
+		var dates []string
+		for _, a := range actual {
+			dates = append(dates, a.Format("2006-01-02"))
+		}
+		if strings.Join(dates, ",") != strings.Join(p.expect, ",") {
+			t.Errorf("expand(%q) = %v; want %v", p.rule, dates, p.expect)
+		}
+	}
+}
+
+// RFC 5545 の WKST の例
+func TestRecurrence_expand_wkst(t *testing.T) {
+	start := time.Date(1997, 8, 5, 9, 0, 0, 0, time.UTC)
+	params := []struct {
+		rule   string
+		expect string
+	}{
+		{rule: "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO", expect: "1997-08-05,1997-08-10,1997-08-19,1997-08-24"},
+		{rule: "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU", expect: "1997-08-05,1997-08-17,1997-08-19,1997-08-31"},
+	}
+	for _, p := range params {
+		r, err := parseRecurrence(p.rule, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, _ := r.expand(start, 0)
+		var dates []string
+		for _, a := range actual {
+			dates = append(dates, a.Format("2006-01-02"))
+		}
+		if strings.Join(dates, ",") != p.expect {
+			t.Errorf("expand(%q) = %v; want %s", p.rule, dates, p.expect)
+		}
+	}
+}
+
+func TestRecurrence_expand_hourly(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	start := time.Date(2024, 3, 10, 0, 0, 0, 0, loc)
+	r, err := parseRecurrence("FREQ=HOURLY;INTERVAL=2;COUNT=3", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, _ := r.expand(start, 0)
+	// 夏時間の開始で 2 時がないので経過時間で 2 時間ごと
+	expect := []string{"00:00 EST", "03:00 EDT", "05:00 EDT"}
+	for i, a := range actual {
+		if a.Format("15:04 MST") != expect[i] {
+			t.Errorf("expand()[%d] = %s; want %s", i, a.Format("15:04 MST"), expect[i])
+		}
+	}
+}
+
+func TestParseRecurrence_error(t *testing.T) {
+	params := []struct {
+		rule   string
+		expect string
+	}{
+		{rule: "COUNT=3", expect: "'COUNT=3' needs FREQ."},
+		{rule: "FREQ=FORTNIGHTLY", expect: "'FORTNIGHTLY' is invalid FREQ."},
+		{rule: "FREQ=DAILY;COUNT=0", expect: "'0' is invalid COUNT."},
+		{rule: "FREQ=MONTHLY;BYDAY=TUE", expect: "'TUE' is invalid BYDAY."},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=32", expect: "'32' is invalid BYMONTHDAY."},
+		{rule: "FREQ=YEARLY;BYMONTH=-1", expect: "'-1' is invalid BYMONTH."},
+		{rule: "FREQ=DAILY;BYHOUR=9", expect: "'BYHOUR' is not supported."},
+		{rule: "FREQ=DAILY;COUNT=2;UNTIL=20240101", expect: "COUNT and UNTIL cannot be used together."},
+		{rule: "FREQ=DAILY;COUNT", expect: "'FREQ=DAILY;COUNT' is invalid RRULE."},
+		{rule: "RRULE:FREQ=DAILY\nEXDATE;TZID=Mars/Olympus:20240101T000000", expect: "'Mars/Olympus' is invalid time zone."},
+	}
+	for _, p := range params {
+		if _, err := parseRecurrence(p.rule, time.UTC); err == nil || err.Error() != p.expect {
+			t.Errorf("parseRecurrence(%q) = %v; want %s", p.rule, err, p.expect)
+		}
+	}
+}
+
+func TestRun_rrule(t *testing.T) {
+	nowInterface = &MyTime{}
+	params := []struct {
+		args   []string
+		expect string
+	}{
+		{args: []string{AppName, "rrule", "FREQ=MONTHLY;BYDAY=2TU;COUNT=3", "2024-01-09 10:00:00"}, expect: "2024-01-09 10:00:00\n2024-02-13 10:00:00\n2024-03-12 10:00:00\n"},
+		{args: []string{AppName, "rrule", "FREQ=DAILY", "--exdate", "20240110T100000,2024-01-12 10:00:00", "-n", "3", "-o", "YMD-", "2024-01-09 10:00:00"}, expect: "2024-01-09\n2024-01-11\n2024-01-13\n"},
+	}
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+		if status := clo.Run(p.args); status != ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != p.expect {
+			t.Errorf("Run(%s): Output = %q; want %q", p.args, outStream.String(), p.expect)
+		}
+	}
+
+	// COUNT も UNTIL もないときは 10 個
+	outStream := new(bytes.Buffer)
+	clo := &CLO{outStream: outStream, errStream: new(bytes.Buffer)}
+	clo.Run([]string{AppName, "rrule", "FREQ=WEEKLY"})
+	if n := strings.Count(outStream.String(), "\n"); n != rruleDefaultLimit {
+		t.Errorf("Run(rrule FREQ=WEEKLY): %d lines; want %d", n, rruleDefaultLimit)
+	}
+
+	errors := []struct {
+		args   []string
+		expect string
+	}{
+		{args: []string{AppName, "rrule"}, expect: "rrule needs a RRULE."},
+		{args: []string{AppName, "rrule", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", "2024-01-01"}, expect: "has no occurrences."},
+		{args: []string{AppName, "rrule", "FREQ=DAILY", "1D"}, expect: "rrule needs a date."},
+		{args: []string{AppName, "rrule", "FREQ=DAILY", "--exdate", "someday"}, expect: "'someday' is invalid format."},
+	}
+	for _, p := range errors {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+		if status := clo.Run(p.args); status == ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want error", p.args, status)
+		}
+		if strings.Contains(errStream.String(), p.expect) == false {
+			t.Errorf("Run(%s): Error = %v; want %v", p.args, errStream.String(), p.expect)
+		}
+	}
+}
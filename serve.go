@@ -0,0 +1,446 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+const (
+	defaultAddr      = "127.0.0.1:8080"
+	maxQueryLength   = 4096
+	maxHeaderBytes   = 16 << 10
+	defaultSeqCount  = 10
+	serverReadLimit  = 10 * time.Second
+	serverWriteLimit = 10 * time.Second
+)
+
+func serveCommand() cli.Command {
+	return cli.Command{
+		Name:      "serve",
+		Usage:     "HTTP/JSON API サーバーを起動します",
+		UsageText: AppName + " serve [options]",
+		Description: `/eval, /diff, /seq, /guess, /health を JSON で提供します.
+   -i, -o, -a, --tz はリクエストで i, o, a, tz を省略したときのデフォルトになります.`,
+		HideHelp: true,
+		Flags: commandFlags(
+			cli.StringFlag{
+				Name:  "addr",
+				Value: defaultAddr,
+				Usage: "待ち受けるアドレスを指定します",
+			},
+			cli.IntFlag{
+				Name:  "max-seq",
+				Value: 1000,
+				Usage: "/seq で返す日時の最大数を指定します",
+			},
+			cli.IntFlag{
+				Name:  "max-concurrent",
+				Value: 16,
+				Usage: "同時に処理するリクエストの最大数を指定します",
+			},
+		),
+		Action: commandAction(serve),
+	}
+}
+
+// server HTTP/JSON API サーバー
+type server struct {
+	inputFormat  string
+	outputFormat string
+	adjust       AdjustDay
+	zone         *time.Location
+	maxSeq       int
+	sem          chan struct{}
+}
+
+func serve(c *cli.Context) error {
+	s := &server{
+		inputFormat:  c.String("i"),
+		outputFormat: c.String("o"),
+		adjust:       adjustPolicy(c),
+		zone:         zone,
+		maxSeq:       c.Int("max-seq"),
+		sem:          make(chan struct{}, c.Int("max-concurrent")),
+	}
+
+	srv := &http.Server{
+		Addr:              c.String("addr"),
+		Handler:           s.handler(),
+		ReadHeaderTimeout: serverReadLimit,
+		ReadTimeout:       serverReadLimit,
+		WriteTimeout:      serverWriteLimit,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		<-sig
+		ctx, cancel := context.WithTimeout(context.Background(), serverWriteLimit)
+		defer cancel()
+		srv.Shutdown(ctx)
+		close(done)
+	}()
+
+	fmt.Fprintf(clo.errStream, "listening on http://%s\n", srv.Addr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	<-done
+	return nil
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.limit(s.health))
+	mux.HandleFunc("/eval", s.limit(s.eval))
+	mux.HandleFunc("/diff", s.limit(s.diff))
+	mux.HandleFunc("/seq", s.limit(s.seq))
+	mux.HandleFunc("/guess", s.limit(s.guess))
+	return mux
+}
+
+// httpError ステータスコードをもつエラー
+type httpError struct {
+	status int
+	msg    string
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+type handlerFunc func(r *http.Request) (interface{}, error)
+
+// limit リクエストの制限を確認してから h を呼び出し, 結果を JSON で返す.
+func (s *server) limit(h handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var result interface{}
+		var err error
+		switch {
+		case r.Method != http.MethodGet && r.Method != http.MethodHead:
+			err = &httpError{status: http.StatusMethodNotAllowed, msg: "method not allowed."}
+		case len(r.URL.RawQuery) > maxQueryLength:
+			err = &httpError{status: http.StatusRequestURITooLong, msg: "query is too long."}
+		default:
+			select {
+			case s.sem <- struct{}{}:
+				result, err = h(r)
+				<-s.sem
+			default:
+				err = &httpError{status: http.StatusServiceUnavailable, msg: "too many requests."}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err != nil {
+			status, kind := errorStatus(err)
+			log.Printf("%s %s: %d %v", r.Method, r.URL, status, err)
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": map[string]string{"kind": kind, "message": err.Error()},
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// errorStatus エラーの種類に対応するステータスコード. 解釈できないときは 400, 計算できないときは 422 です.
+func errorStatus(err error) (int, string) {
+	var he *httpError
+	if errors.As(err, &he) {
+		return he.status, "request"
+	}
+	if errorKind(err) == EvalError {
+		return http.StatusUnprocessableEntity, EvalError.String()
+	}
+	return http.StatusBadRequest, ParseError.String()
+}
+
+// requestEvaluator リクエストのパラメータ i, a, tz で evaluator を作る.
+func (s *server) requestEvaluator(r *http.Request) (*evaluator, error) {
+	q := r.URL.Query()
+	inputFormat := s.inputFormat
+	if v, ok := q["i"]; ok {
+		inputFormat = v[0]
+	}
+	e := newEvaluator(inputFormat, s.adjust)
+	e.zone = s.zone
+
+	if v := q.Get("a"); v != "" {
+		adjust, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is invalid adjust.", v)
+		}
+		e.adjust = adjustDay(adjust)
+	}
+	if v := q.Get("on-invalid-day"); v != "" {
+		adjust, err := parseAdjustDay(v)
+		if err != nil {
+			return nil, err
+		}
+		e.adjust = adjust
+	}
+	if v := q.Get("tz"); v != "" {
+		loc, err := time.LoadLocation(v)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is invalid time zone.", v)
+		}
+		e.zone = loc
+	}
+	return e, nil
+}
+
+// evalDate base を評価してから exprs を続けて評価する. base が空のときは現在時刻から計算します.
+func evalDate(e *evaluator, base string, exprs []string) (value, error) {
+	if base == "" {
+		e.current = value{dt: &Dt{time: now(), format: defaultFormat}}
+	} else if err := e.first(base); err != nil {
+		return value{}, err
+	}
+	for _, expr := range exprs {
+		if err := e.rest(expr); err != nil {
+			return value{}, err
+		}
+	}
+	return e.current, nil
+}
+
+type dateResult struct {
+	Result   string `json:"result"`
+	Unix     *int64 `json:"unix,omitempty"`
+	RFC3339  string `json:"rfc3339,omitempty"`
+	Duration bool   `json:"duration,omitempty"`
+}
+
+func (s *server) formatValue(r *http.Request, e *evaluator, v value) (dateResult, error) {
+	q := r.URL.Query()
+	outputFormat := s.outputFormat
+	if o, ok := q["o"]; ok {
+		outputFormat = o[0]
+	}
+
+	if v.dt == nil {
+		str, err := formatDuration(v, outputFormat)
+		return dateResult{Result: str, Duration: true}, err
+	}
+
+	t := v.dt.time
+	if e.zone != nil {
+		t = t.In(e.zone)
+	}
+	unix := t.Unix()
+	result := dateResult{Unix: &unix, RFC3339: t.Format(time.RFC3339Nano)}
+
+	switch outputFormat {
+	case relativeFormat, diffFormat, diffISOFormat:
+		ref := now()
+		if q.Get("ref") != "" {
+			refEvaluator := newEvaluator(e.inputFormat, e.adjust)
+			refEvaluator.zone = e.zone
+			refValue, err := evalDate(refEvaluator, q.Get("ref"), nil)
+			if err != nil {
+				return result, err
+			}
+			if refValue.dt == nil {
+				return result, evalError(errors.New("ref must be a date."))
+			}
+			ref = refValue.dt.time
+		}
+		switch outputFormat {
+		case relativeFormat:
+			lang := q.Get("lang")
+			if lang == "" {
+				lang = "en"
+			}
+			str, err := Humanize(t, ref, RelativeOption{Granularity: 1, Lang: lang})
+			result.Result = str
+			return result, err
+		case diffFormat:
+			result.Result = t.Sub(ref).String()
+		default:
+			result.Result = durationBetween(ref, t).ISOString()
+		}
+		return result, nil
+	case icsFormat:
+		return result, evalError(errors.New("-o ics cannot be used here."))
+	default:
+		str, err := formatDt(v.dt, outputFormat, e.zone)
+		result.Result = str
+		return result, err
+	}
+}
+
+func (s *server) health(r *http.Request) (interface{}, error) {
+	return map[string]string{"status": "ok", "version": version}, nil
+}
+
+// eval /eval?base=...&expr=...&o=...
+func (s *server) eval(r *http.Request) (interface{}, error) {
+	e, err := s.requestEvaluator(r)
+	if err != nil {
+		return nil, err
+	}
+	q := r.URL.Query()
+	v, err := evalDate(e, q.Get("base"), q["expr"])
+	if err != nil {
+		return nil, err
+	}
+	return s.formatValue(r, e, v)
+}
+
+// diff /diff?from=...&to=...
+func (s *server) diff(r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	var times []time.Time
+	for _, name := range []string{"from", "to"} {
+		if q.Get(name) == "" {
+			return nil, fmt.Errorf("%s is required.", name)
+		}
+		e, err := s.requestEvaluator(r)
+		if err != nil {
+			return nil, err
+		}
+		v, err := evalDate(e, q.Get(name), nil)
+		if err != nil {
+			return nil, err
+		}
+		if v.dt == nil {
+			return nil, evalError(fmt.Errorf("%s must be a date.", name))
+		}
+		times = append(times, v.dt.time)
+	}
+
+	d := times[1].Sub(times[0])
+	return map[string]interface{}{
+		"iso":     durationBetween(times[0], times[1]).ISOString(),
+		"go":      d.String(),
+		"seconds": d.Seconds(),
+	}, nil
+}
+
+// seq /seq?base=...&step=+1D&count=10&until=...
+// k 番目の日時は base に step を k 倍した期間を加算したものです.
+func (s *server) seq(r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	e, err := s.requestEvaluator(r)
+	if err != nil {
+		return nil, err
+	}
+	base, err := evalDate(e, q.Get("base"), nil)
+	if err != nil {
+		return nil, err
+	}
+	if base.dt == nil {
+		return nil, evalError(errors.New("base must be a date."))
+	}
+
+	if q.Get("step") == "" {
+		return nil, errors.New("step is required.")
+	}
+	step, err := evalDate(e, q.Get("step"), nil)
+	if err != nil {
+		return nil, err
+	}
+	if step.dt != nil {
+		return nil, evalError(errors.New("step must be a duration."))
+	}
+
+	count := defaultSeqCount
+	if v := q.Get("count"); v != "" {
+		if count, err = strconv.Atoi(v); err != nil || count < 0 {
+			return nil, fmt.Errorf("'%s' is invalid count.", v)
+		}
+	}
+	var until *Dt
+	if v := q.Get("until"); v != "" {
+		u, err := evalDate(e, v, nil)
+		if err != nil {
+			return nil, err
+		}
+		if u.dt == nil {
+			return nil, evalError(errors.New("until must be a date."))
+		}
+		until = u.dt
+		if q.Get("count") == "" {
+			count = s.maxSeq
+		}
+	}
+	if count > s.maxSeq {
+		return nil, &httpError{status: http.StatusRequestEntityTooLarge, msg: fmt.Sprintf("count must be less than or equal to %d.", s.maxSeq)}
+	}
+
+	results := []dateResult{}
+	// count を省略して until を指定したときは, until までの日時が maxSeq に収まるかも確かめる
+	truncatable := until != nil && q.Get("count") == ""
+	for k := 0; k < count || (truncatable && k == count); k++ {
+		dt, err := applyOffset(base.dt, scaleOffset(step.offset, k), e.adjust)
+		if err != nil {
+			return nil, evalError(err)
+		}
+		if until != nil && dt.time.After(until.time) {
+			break
+		}
+		if k == count {
+			return nil, evalError(fmt.Errorf("until has more than %d dates.", s.maxSeq))
+		}
+		result, err := s.formatValue(r, e, value{dt: dt})
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return map[string]interface{}{"results": results}, nil
+}
+
+// guess /guess?value=... 値を解釈できるフォーマットの一覧を返す.
+func (s *server) guess(r *http.Request) (interface{}, error) {
+	e, err := s.requestEvaluator(r)
+	if err != nil {
+		return nil, err
+	}
+	input := r.URL.Query().Get("value")
+	if input == "" {
+		return nil, errors.New("value is required.")
+	}
+
+	type candidate struct {
+		Name    string `json:"name"`
+		Layout  string `json:"layout"`
+		RFC3339 string `json:"rfc3339"`
+	}
+	candidates := []candidate{}
+	names := []string{unixSeconds, unixMilliSeconds}
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names[2:])
+	for _, name := range names {
+		layout := name
+		if v, ok := formats[name]; ok {
+			layout = v
+		}
+		dt, err := parseDate(input, layout, e.zone)
+		if err != nil || dt.format != layout {
+			continue
+		}
+		candidates = append(candidates, candidate{Name: name, Layout: layout, RFC3339: dt.time.Format(time.RFC3339Nano)})
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("'%s' is invalid format.", input)
+	}
+	return map[string]interface{}{"value": input, "candidates": candidates}, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newTestServer() *httptest.Server {
+	s := &server{adjust: Normalize, maxSeq: 5, sem: make(chan struct{}, 1)}
+	return httptest.NewServer(s.handler())
+}
+
+func TestServer(t *testing.T) {
+	nowInterface = &MyTime{}
+	ts := newTestServer()
+	defer ts.Close()
+
+	params := []struct {
+		path   string
+		status int
+		expect string
+	}{
+		{path: "/health", status: http.StatusOK, expect: `"status":"ok"`},
+		{path: "/eval", status: http.StatusOK, expect: `"result":"2018/05/12 17:30:00"`},
+		{path: "/eval?base=2024-01-31&expr=%2B1M", status: http.StatusOK, expect: `"result":"2024-03-02"`},
+		{path: "/eval?base=2024-01-31&expr=%2B1M&a=true&o=RFC3339", status: http.StatusOK, expect: `"result":"2024-02-29T00:00:00+09:00"`},
+		{path: "/eval?base=2024-01-01&tz=UTC&o=RFC3339", status: http.StatusOK, expect: `"result":"2024-01-01T00:00:00Z"`},
+		{path: "/eval?base=2024-03-01+-+2024-01-01", status: http.StatusOK, expect: `"result":"P2M","duration":true`},
+		{path: "/eval?base=now&expr=-3D&o=relative", status: http.StatusOK, expect: `"result":"3 days ago"`},
+		{path: "/eval?base=2024-01-31+%2B+1x", status: http.StatusBadRequest, expect: `"kind":"parse"`},
+		{path: "/eval?base=now+%2B+now", status: http.StatusUnprocessableEntity, expect: `"kind":"eval"`},
+		{path: "/eval?tz=Mars/Olympus", status: http.StatusBadRequest, expect: `'Mars/Olympus' is invalid time zone.`},
+		{path: "/diff?from=2024-01-01&to=2024-03-01+12:00", status: http.StatusOK, expect: `"iso":"P2MT12H"`},
+		{path: "/diff?from=2024-01-01", status: http.StatusBadRequest, expect: `to is required.`},
+		{path: "/diff?from=2024-01-01&to=1D", status: http.StatusUnprocessableEntity, expect: `to must be a date.`},
+		{path: "/seq?base=2024-01-31&step=%2B1M&count=3", status: http.StatusOK, expect: `{"result":"2024-03-02"`},
+		{path: "/seq?base=2024-01-31&step=%2B1M&count=3&a=1", status: http.StatusOK, expect: `{"result":"2024-02-29"`},
+		{path: "/seq?base=2024-01-01&step=%2B1W&until=2024-01-10", status: http.StatusOK, expect: `"2024-01-08","unix":1704639600,"rfc3339":"2024-01-08T00:00:00+09:00"}]}`},
+		{path: "/seq?base=2024-01-01&step=%2B1D&until=2024-01-05", status: http.StatusOK, expect: `{"result":"2024-01-05"`},
+		{path: "/seq?base=2024-01-01&step=%2B1D&until=2024-01-06", status: http.StatusUnprocessableEntity, expect: `until has more than 5 dates.`},
+		{path: "/seq?base=2024-01-01&step=%2B1D&until=2024-01-31&count=2", status: http.StatusOK, expect: `"2024-01-02","unix"`},
+		{path: "/seq?base=2024-01-01&step=%2B1D&count=6", status: http.StatusRequestEntityTooLarge, expect: `"kind":"request"`},
+		{path: "/seq?base=2024-01-01&step=2024-01-02", status: http.StatusUnprocessableEntity, expect: `step must be a duration.`},
+		{path: "/guess?value=2018-05-12", status: http.StatusOK, expect: `"name":"YMD-","layout":"2006-01-02"`},
+		{path: "/guess?value=foo", status: http.StatusBadRequest, expect: `'foo' is invalid format.`},
+		{path: "/eval?base=" + strings.Repeat("x", maxQueryLength), status: http.StatusRequestURITooLong, expect: `query is too long.`},
+	}
+
+	for _, p := range params {
+		res, err := http.Get(ts.URL + p.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != p.status {
+			t.Errorf("GET %s: StatusCode = %d; want %d", p.path, res.StatusCode, p.status)
+		}
+		if strings.Contains(string(body), p.expect) == false {
+			t.Errorf("GET %s: Body = %s; want %s", p.path, body, p.expect)
+		}
+	}
+}
+
+func TestServer_methodNotAllowed(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/eval", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST /eval: StatusCode = %d; want %d", res.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestServer_concurrent(t *testing.T) {
+	// 同時のリクエストでも夏時間の警告や現在時刻の読み書きが競合しない
+	nowInterface = &MyTime{}
+	errStream := new(bytes.Buffer)
+	clo = &CLO{outStream: new(bytes.Buffer), errStream: errStream}
+	warnDST = true
+	defer func() { warnDST = false }()
+
+	s := &server{adjust: Normalize, maxSeq: 5, sem: make(chan struct{}, 32)}
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	paths := []string{
+		"/eval?base=2024-03-09+12:00&expr=%2B1D&tz=America/New_York",
+		"/eval?base=2024-03-10+02:30&tz=America/New_York",
+		"/seq?base=now&step=%2B1D&count=3",
+		"/eval?base=now&expr=-3D&o=relative",
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			res, err := http.Get(ts.URL + path)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("GET %s: StatusCode = %d; want %d", path, res.StatusCode, http.StatusOK)
+			}
+		}(paths[i%len(paths)])
+	}
+	wg.Wait()
+}
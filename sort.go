@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+func sortCommand() cli.Command {
+	return cli.Command{
+		Name:      "sort",
+		Usage:     "行を日時の順に並べ替えます",
+		UsageText: AppName + " sort [options] [file [file ...]]",
+		Description: `各行から日時を取り出し, タイムゾーンやフォーマットによらず時刻の順に安定ソートします.
+   日時は -f のフィールド, -p の正規表現, -i のレイアウトのいずれかで取り出します.
+   日時のない行は直前の行と一緒に移動します. file を省略したときや - のときは標準入力を読みます.
+   --merge のときは並べ替え済みのファイルを全部読み込まずにマージします.`,
+		HideHelp: true,
+		Flags: commandFlags(append(extractFlags(),
+			cli.BoolFlag{
+				Name:  "reverse, r",
+				Usage: "新しい順に並べます",
+			},
+			cli.BoolFlag{
+				Name:  "merge, m",
+				Usage: "並べ替え済みのファイルをマージします",
+			},
+		)...),
+		Action: commandAction(sortLines),
+	}
+}
+
+// record 日時とそれに続く日時のない行をまとめたもの
+type record struct {
+	time time.Time
+	text string
+}
+
+// recordReader 入力からひとつずつ record を読む.
+type recordReader struct {
+	reader  *bufio.Reader
+	extract func(string) (string, bool)
+	// next 先読みした日時のある行
+	next *record
+	eof  bool
+}
+
+func newRecordReader(in io.Reader, extract func(string) (string, bool)) *recordReader {
+	return &recordReader{reader: bufio.NewReaderSize(in, 64*1024), extract: extract}
+}
+
+// read 次の record を読む. 入力の終わりでは io.EOF を返します.
+// 最初の行に日時がないときは時刻のゼロ値の record になります.
+func (r *recordReader) read() (record, error) {
+	var current *record
+	if r.next != nil {
+		current, r.next = r.next, nil
+	}
+
+	for r.eof == false {
+		line, err := r.reader.ReadString('\n')
+		if err == io.EOF {
+			r.eof = true
+		} else if err != nil {
+			return record{}, err
+		}
+		if line == "" {
+			break
+		}
+		if strings.HasSuffix(line, "\n") == false {
+			line += "\n"
+		}
+
+		t, ok := lineTimestamp(r.extract, strings.TrimRight(line, "\r\n"))
+		switch {
+		case current == nil:
+			current = &record{time: t, text: line}
+		case ok:
+			r.next = &record{time: t, text: line}
+			return *current, nil
+		default:
+			current.text += line
+		}
+	}
+
+	if current == nil {
+		return record{}, io.EOF
+	}
+	return *current, nil
+}
+
+func sortLines(c *cli.Context) error {
+	extract, err := timestampExtractor(c)
+	if err != nil {
+		return err
+	}
+
+	var inputs []io.Reader
+	for _, name := range c.Args() {
+		if name == "-" {
+			inputs = append(inputs, clo.inStream)
+			continue
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		inputs = append(inputs, f)
+	}
+	if len(inputs) == 0 {
+		inputs = append(inputs, clo.inStream)
+	}
+
+	var readers []*recordReader
+	for _, in := range inputs {
+		readers = append(readers, newRecordReader(in, extract))
+	}
+
+	writer := bufio.NewWriterSize(clo.outStream, 64*1024)
+	defer writer.Flush()
+	if c.Bool("merge") {
+		return mergeRecords(readers, c.Bool("reverse"), writer)
+	}
+	return sortRecords(readers, c.Bool("reverse"), writer)
+}
+
+// sortRecords すべての record を読み込んで安定ソートする.
+func sortRecords(readers []*recordReader, reverse bool, w io.Writer) error {
+	var records []record
+	for _, r := range readers {
+		for {
+			rec, err := r.read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			records = append(records, rec)
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if reverse {
+			return records[i].time.After(records[j].time)
+		}
+		return records[i].time.Before(records[j].time)
+	})
+	for _, rec := range records {
+		if _, err := io.WriteString(w, rec.text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeItem マージ中の各入力の先頭の record
+type mergeItem struct {
+	rec   record
+	index int
+}
+
+// mergeHeap 時刻の順, 同じ時刻のときは入力の順に取り出すヒープ
+type mergeHeap struct {
+	items   []mergeItem
+	reverse bool
+}
+
+func (h *mergeHeap) Len() int      { return len(h.items) }
+func (h *mergeHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *mergeHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if a.rec.time.Equal(b.rec.time) == false {
+		return a.rec.time.Before(b.rec.time) != h.reverse
+	}
+	return a.index < b.index
+}
+func (h *mergeHeap) Push(x interface{}) { h.items = append(h.items, x.(mergeItem)) }
+func (h *mergeHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// mergeRecords 並べ替え済みの入力を k-way マージする. 各入力は先頭の record だけを保持します.
+func mergeRecords(readers []*recordReader, reverse bool, w io.Writer) error {
+	h := &mergeHeap{reverse: reverse}
+	for i, r := range readers {
+		rec, err := r.read()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		h.items = append(h.items, mergeItem{rec: rec, index: i})
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		item := h.items[0]
+		if _, err := io.WriteString(w, item.rec.text); err != nil {
+			return err
+		}
+
+		rec, err := readers[item.index].read()
+		switch {
+		case err == io.EOF:
+			heap.Pop(h)
+		case err != nil:
+			return err
+		default:
+			h.items[0].rec = rec
+			heap.Fix(h, 0)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_sort(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	host1 := filepath.Join(dir, "host1.log")
+	host2 := filepath.Join(dir, "host2.log")
+	files := map[string]string{
+		host1: "2024-01-31T10:00:05+09:00 b\n2024-01-31T10:00:20+09:00 d\n  trace d\n",
+		host2: "2024-01-31T01:00:00Z a\n2024-01-31T01:00:10Z c\n2024-01-31T01:00:20Z e",
+	}
+	for path, content := range files {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	params := []struct {
+		args   []string
+		stdin  string
+		expect []string
+	}{
+		{
+			args:   []string{"-f", "1", host1, host2},
+			expect: []string{"2024-01-31T01:00:00Z a", "2024-01-31T10:00:05+09:00 b", "2024-01-31T01:00:10Z c", "2024-01-31T10:00:20+09:00 d", "  trace d", "2024-01-31T01:00:20Z e"},
+		},
+		{
+			args:   []string{"-f", "1", "--merge", host1, host2},
+			expect: []string{"2024-01-31T01:00:00Z a", "2024-01-31T10:00:05+09:00 b", "2024-01-31T01:00:10Z c", "2024-01-31T10:00:20+09:00 d", "  trace d", "2024-01-31T01:00:20Z e"},
+		},
+		{
+			args:   []string{"-f", "1", "-r", host1, host2},
+			expect: []string{"2024-01-31T10:00:20+09:00 d", "  trace d", "2024-01-31T01:00:20Z e", "2024-01-31T01:00:10Z c", "2024-01-31T10:00:05+09:00 b", "2024-01-31T01:00:00Z a"},
+		},
+		{
+			args:   []string{"-p", `at (.+)$`, "-"},
+			stdin:  "x at 2024/01/31 10:00:00\ny at 2024-01-31 09:00\nz at 1706655600\n",
+			expect: []string{"z at 1706655600", "y at 2024-01-31 09:00", "x at 2024/01/31 10:00:00"},
+		},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{inStream: strings.NewReader(p.stdin), outStream: outStream, errStream: errStream}
+
+		status := clo.Run(append([]string{AppName, "sort"}, p.args...))
+		if status != ExitCodeOK {
+			t.Fatalf("Run(%v): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream)
+		}
+		expect := strings.Join(p.expect, "\n") + "\n"
+		if outStream.String() != expect {
+			t.Errorf("Run(%v): Output = %q; want %q", p.args, outStream, expect)
+		}
+	}
+}
+
+func TestRecordReader(t *testing.T) {
+	extract, _ := fieldExtractor("1", "")
+	r := newRecordReader(strings.NewReader("header\n2024-01-31 a\n  more\n2024-02-01 b"), extract)
+
+	expect := []string{"header\n", "2024-01-31 a\n  more\n", "2024-02-01 b\n"}
+	for _, e := range expect {
+		rec, err := r.read()
+		if err != nil || rec.text != e {
+			t.Errorf("read() = %q, %v; want %q", rec.text, err, e)
+		}
+	}
+	if _, err := r.read(); err != io.EOF {
+		t.Errorf("read() = %v; want EOF", err)
+	}
+}
+
+func TestRun_sortError(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	params := [][]string{
+		{},
+		{"-f", "1", filepath.Join(t.TempDir(), "missing.log")},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{inStream: strings.NewReader(""), outStream: outStream, errStream: errStream}
+
+		status := clo.Run(append([]string{AppName, "sort"}, p...))
+		if status != ExitCodeError {
+			t.Errorf("Run(%v): ExitStatus = %d; want %d", p, status, ExitCodeError)
+		}
+	}
+}
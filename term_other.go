@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// makeRaw この OS では行編集に対応していない.
+func makeRaw(f *os.File) (func(), error) {
+	return nil, errors.New("line editing is not supported.")
+}
+
+// isTerminal この OS では端末かどうかを判定しない.
+func isTerminal(f *os.File) bool {
+	return false
+}
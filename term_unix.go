@@ -0,0 +1,44 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw 端末を 1 文字ずつ読めるモードにする. 戻り値の関数で元に戻します.
+// 端末でないときはエラーを返します.
+func makeRaw(f *os.File) (func(), error) {
+	var old syscall.Termios
+	if err := ioctlTermios(f.Fd(), ioctlGetTermios, &old); err != nil {
+		return nil, err
+	}
+
+	raw := old
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN | syscall.ISIG
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctlTermios(f.Fd(), ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+	return func() {
+		ioctlTermios(f.Fd(), ioctlSetTermios, &old)
+	}, nil
+}
+
+func ioctlTermios(fd uintptr, request uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// isTerminal f が端末のときは true を返す.
+func isTerminal(f *os.File) bool {
+	var t syscall.Termios
+	return ioctlTermios(f.Fd(), ioctlGetTermios, &t) == nil
+}
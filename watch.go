@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// watchSleep watch の間隔を待つ. テストで置き換えます.
+var watchSleep = time.Sleep
+
+func watchCommand() cli.Command {
+	return cli.Command{
+		Name:      "watch",
+		Usage:     "計算式を一定の間隔で評価し続けます",
+		UsageText: AppName + " watch [options] [date [expr [expr ...]]]",
+		Description: `計算式を --interval ごとに評価して表示します. 端末のときはその場で書き換え,
+   そうでないときは 1 回ごとに 1 行を出力します.
+   --countdown のときは計算した日時までの残り時間を表示し, その日時になったら終了します.`,
+		HideHelp: true,
+		Flags: commandFlags(
+			cli.DurationFlag{
+				Name:  "interval, n",
+				Value: time.Second,
+				Usage: "評価する間隔を指定します",
+			},
+			cli.IntFlag{
+				Name:  "count, c",
+				Usage: "評価する回数を指定します (0 は無制限)",
+			},
+			cli.BoolFlag{
+				Name:  "countdown",
+				Usage: "計算した日時までの残り時間を表示し, その日時になったら終了します",
+			},
+		),
+		Action: commandAction(watch),
+	}
+}
+
+// watcher watch の状態
+type watcher struct {
+	c         *cli.Context
+	countdown bool
+	// redraw 端末のときは前回の表示を消して書き換える
+	redraw bool
+	// lines 前回表示した行数
+	lines int
+}
+
+func watch(c *cli.Context) error {
+	interval := c.Duration("n")
+	if interval <= 0 {
+		return fmt.Errorf("'%v' is invalid interval.", interval)
+	}
+	count := c.Int("c")
+	if count < 0 {
+		return fmt.Errorf("'%d' is invalid count.", count)
+	}
+
+	w := &watcher{c: c, countdown: c.Bool("countdown")}
+	f, ok := clo.outStream.(*os.File)
+	w.redraw = ok && isTerminal(f)
+
+	for i := 0; count == 0 || i < count; i++ {
+		if i > 0 {
+			watchSleep(interval)
+			// 固定した現在時刻からも時計を進める
+			if pinnedNow != nil {
+				t := pinnedNow.Add(interval)
+				pinnedNow = &t
+			}
+		}
+		done, err := w.tick()
+		if err != nil || done {
+			return err
+		}
+	}
+	return nil
+}
+
+// tick 計算式を評価して表示する. カウントダウンが終わったときは true を返します.
+func (w *watcher) tick() (bool, error) {
+	v, err := evalArgs(w.c, w.c.Args())
+	if err != nil {
+		return false, err
+	}
+
+	var s string
+	done := false
+	switch {
+	case w.countdown:
+		if v.dt == nil {
+			return false, evalError(errors.New("countdown needs a date."))
+		}
+		remaining := v.dt.time.Sub(now())
+		if remaining <= 0 {
+			remaining = 0
+			done = true
+		}
+		s = formatRemaining(remaining, w.c.String("o"))
+	case len(worldZones) > 0:
+		s, err = zoneTable(v.dt, worldZones, w.c.String("o"))
+	case v.dt == nil:
+		s, err = formatDuration(v, w.c.String("o"))
+	default:
+		s, err = formatOutput(v.dt, w.c.String("o"))
+	}
+	if err != nil {
+		return false, err
+	}
+
+	w.draw(s)
+	return done, nil
+}
+
+// draw 端末のときは前回の表示をカーソルを戻して消してから表示する.
+func (w *watcher) draw(s string) {
+	if w.redraw && w.lines > 0 {
+		fmt.Fprintf(clo.outStream, "\x1b[%dA\x1b[J", w.lines)
+	}
+	fmt.Fprintf(clo.outStream, "%s\n", s)
+	w.lines = strings.Count(s, "\n") + 1
+}
+
+// formatRemaining 残り時間を秒単位に切り上げて Go 形式か, -o diff-iso のときは ISO 8601 形式にする.
+func formatRemaining(d time.Duration, outputFormat string) string {
+	if r := d % time.Second; r > 0 {
+		d += time.Second - r
+	}
+	if outputFormat == diffISOFormat {
+		return Duration{Clock: d}.ISOString()
+	}
+	return d.String()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun_watch(t *testing.T) {
+	nowInterface = &MyTime{}
+	watchSleep = func(time.Duration) {}
+	defer func() { watchSleep = time.Sleep }()
+
+	params := []struct {
+		args   []string
+		expect string
+	}{
+		{args: []string{AppName, "watch", "-c", "2", "--now", "2024/01/01 00:00:00", "-n", "1h", "now"}, expect: "2024/01/01 00:00:00\n2024/01/01 01:00:00\n"},
+		{args: []string{AppName, "watch", "-c", "1", "2024-03-01 - 2024-01-01"}, expect: "P2M\n"},
+		{args: []string{AppName, "watch", "--countdown", "--now", "2024/01/01 00:00:00", "-n", "1s", "2024/01/01 00:00:02"}, expect: "2s\n1s\n0s\n"},
+		{args: []string{AppName, "watch", "--countdown", "--now", "2024/01/01 00:00:00", "-n", "1h", "-o", "diff-iso", "2024/01/01 01:30:00"}, expect: "PT1H30M\nPT30M\nPT0S\n"},
+		{args: []string{AppName, "watch", "--countdown", "--now", "2024/01/01 00:00:00", "2023/12/31"}, expect: "0s\n"},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+
+		args := p.args
+		status := clo.Run(args)
+		if status != ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want %d, %s", args, status, ExitCodeOK, errStream.String())
+		}
+
+		actual := outStream.String()
+		if actual != p.expect {
+			t.Errorf("Run(%s): Output = %q; want %q", args, actual, p.expect)
+		}
+	}
+}
+
+func TestRun_watchError(t *testing.T) {
+	nowInterface = &MyTime{}
+	params := []struct {
+		args   []string
+		expect string
+	}{
+		{args: []string{AppName, "watch", "-n", "0s", "now"}, expect: "'0s' is invalid interval."},
+		{args: []string{AppName, "watch", "-c", "-1", "now"}, expect: "'-1' is invalid count."},
+		{args: []string{AppName, "watch", "--countdown", "1D"}, expect: "countdown needs a date."},
+	}
+
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+
+		args := p.args
+		status := clo.Run(args)
+		if status == ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want error", args, status)
+		}
+
+		actual := errStream.String()
+		if strings.Contains(actual, p.expect) == false {
+			t.Errorf("Run(%s): Output = %v; want %v", args, actual, p.expect)
+		}
+	}
+}
+
+func TestWatcher_draw(t *testing.T) {
+	outStream := new(bytes.Buffer)
+	clo = &CLO{outStream: outStream, errStream: new(bytes.Buffer)}
+
+	w := &watcher{redraw: true}
+	w.draw("a\nb")
+	w.draw("c")
+	expect := "a\nb\n\x1b[2A\x1b[Jc\n"
+	if actual := outStream.String(); actual != expect {
+		t.Errorf("watcher.draw() = %q; want %q", actual, expect)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// defaultWorkingHours --working-hours を指定しないときの就業時間
+const defaultWorkingHours = "09:00-18:00"
+
+// defaultWeekend --weekend を指定しないときの週末
+const defaultWeekend = "sat,sun"
+
+// weekdays 曜日の集合
+type weekdays uint8
+
+func (w weekdays) contains(d time.Weekday) bool {
+	return w&(1<<uint(d)) != 0
+}
+
+// weekdayNames 曜日の名前. 省略形も使えます.
+var weekdayNames = map[string]time.Weekday{}
+
+func init() {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		weekdayNames[name] = d
+		weekdayNames[name[:3]] = d
+	}
+}
+
+// parseWeekend "sat,sun" や "fri-sat" のように週末の曜日を読む. "none" のときは週末はありません.
+func parseWeekend(s string) (weekdays, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "none" {
+		return 0, nil
+	}
+	var w weekdays
+	for _, item := range strings.Split(s, ",") {
+		names := strings.SplitN(item, "-", 2)
+		from, ok := weekdayNames[strings.TrimSpace(names[0])]
+		to := from
+		if ok && len(names) == 2 {
+			to, ok = weekdayNames[strings.TrimSpace(names[1])]
+		}
+		if ok == false {
+			return 0, fmt.Errorf("'%s' is invalid weekend.", s)
+		}
+		for d := from; ; d = (d + 1) % 7 {
+			w |= 1 << uint(d)
+			if d == to {
+				break
+			}
+		}
+	}
+	return w, nil
+}
+
+var workingHoursRegexp = regexp.MustCompile(`^(\d{1,2}):(\d{2})\s*-\s*(\d{1,2}):(\d{2})$`)
+
+// workingHours 就業時間. 0 時からの分で, end が start より前のときは日をまたぎます.
+// weekend の曜日は就業時間外です.
+type workingHours struct {
+	start, end int
+	weekend    weekdays
+}
+
+func parseWorkingHours(s string) (workingHours, error) {
+	m := workingHoursRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return workingHours{}, fmt.Errorf("'%s' is invalid working hours.", s)
+	}
+	var minutes [4]int
+	for i := range minutes {
+		minutes[i], _ = strconv.Atoi(m[i+1])
+	}
+	start, end := minutes[0]*60+minutes[1], minutes[2]*60+minutes[3]
+	if minutes[1] >= 60 || minutes[3] >= 60 || start >= 24*60 || end > 24*60 || start == end {
+		return workingHours{}, fmt.Errorf("'%s' is invalid working hours.", s)
+	}
+	return workingHours{start: start, end: end, weekend: 1<<time.Saturday | 1<<time.Sunday}, nil
+}
+
+// contains t のその地域の時刻が就業時間内のときは true を返す. 週末は就業時間外です.
+// 日をまたぐ就業時間は始まった日の曜日で判断します.
+func (h workingHours) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	day := t
+	switch {
+	case h.start < h.end:
+		if m < h.start || h.end <= m {
+			return false
+		}
+	case h.start <= m:
+	case m < h.end:
+		day = t.AddDate(0, 0, -1)
+	default:
+		return false
+	}
+	return h.weekend.contains(day.Weekday()) == false
+}
+
+// zoneEntry --zones のひとつのタイムゾーン
+type zoneEntry struct {
+	name  string
+	loc   *time.Location
+	hours workingHours
+}
+
+// worldZones --zones で指定されたタイムゾーン. 指定されていないときは nil
+var worldZones []zoneEntry
+
+// loadZones --zones と --working-hours と --weekend を読む.
+func loadZones(c *cli.Context) error {
+	worldZones = nil
+	hours, err := parseWorkingHours(c.String("working-hours"))
+	if err != nil {
+		return err
+	}
+	if hours.weekend, err = parseWeekend(c.String("weekend")); err != nil {
+		return err
+	}
+	if s := c.String("zones"); s != "" {
+		if worldZones, err = parseZones(s, hours); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseZones カンマ区切りのタイムゾーンを読む. 設定ファイルの [zones] のグループの名前も指定できます.
+func parseZones(s string, hours workingHours) ([]zoneEntry, error) {
+	var entries []zoneEntry
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		names := []string{item}
+		if group, ok := config.zones[item]; ok {
+			names = group
+		}
+		for _, name := range names {
+			e, err := parseZoneEntry(name, hours)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// parseZoneEntry "Asia/Tokyo" か, 就業時間をつけた "Europe/Berlin@08:00-17:00" を読む.
+// "Asia/Riyadh@09:00-17:00@fri-sat" のように週末もつけられます. 就業時間を省略した "Asia/Riyadh@@fri-sat" も使えます.
+func parseZoneEntry(s string, hours workingHours) (zoneEntry, error) {
+	parts := strings.SplitN(s, "@", 3)
+	name := parts[0]
+	if len(parts) > 1 && parts[1] != "" {
+		h, err := parseWorkingHours(parts[1])
+		if err != nil {
+			return zoneEntry{}, err
+		}
+		hours.start, hours.end = h.start, h.end
+	}
+	if len(parts) > 2 {
+		var err error
+		if hours.weekend, err = parseWeekend(parts[2]); err != nil {
+			return zoneEntry{}, err
+		}
+	}
+	loc, err := loadLocation(name)
+	if err != nil {
+		return zoneEntry{}, err
+	}
+	return zoneEntry{name: name, loc: loc, hours: hours}, nil
+}
+
+// outputZones 日時をタイムゾーンごとに出力する.
+func outputZones(dt *Dt) error {
+	s, err := zoneTable(dt, worldZones, cliContext.String("o"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(clo.outStream, "%s\n", s)
+	return nil
+}
+
+// zoneTable 日時をタイムゾーンごとに, 時差と略称をつけた表にする. 最初のタイムゾーンと日付が異なるときは
+// +1d のように日数の差を, 就業時間外のときは off-hours を表示します.
+func zoneTable(dt *Dt, entries []zoneEntry, outputFormat string) (string, error) {
+	if dt == nil {
+		return "", evalError(errors.New("--zones needs a date."))
+	}
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	first := dt.time.In(entries[0].loc)
+	for _, e := range entries {
+		t := dt.time.In(e.loc)
+		s, err := formatZoned(dt, outputFormat, e.loc)
+		if err != nil {
+			return "", err
+		}
+
+		day := ""
+		if d := calendarDays(first, t); d != 0 {
+			day = fmt.Sprintf("%+dd", d)
+		}
+		hours := ""
+		if e.hours.contains(t) == false {
+			hours = "off-hours"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.name, s, t.Format("-07:00"), t.Format("MST"), day, hours)
+	}
+	w.Flush()
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// formatZoned 日時を loc のタイムゾーンで出力フォーマットの文字列にする.
+func formatZoned(dt *Dt, outputFormat string, loc *time.Location) (string, error) {
+	switch outputFormat {
+	case relativeFormat, diffFormat, diffISOFormat, icsFormat:
+		return formatOutput(dt, outputFormat)
+	}
+	return formatDt(dt, outputFormat, loc)
+}
+
+// calendarDays from の日付から to の日付までの日数. 時刻とタイムゾーンは無視します.
+func calendarDays(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
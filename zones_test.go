@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWorkingHours(t *testing.T) {
+	params := []struct {
+		input  string
+		day    int
+		at     []int
+		expect []bool
+	}{
+		{input: "09:00-18:00", at: []int{8, 9, 17, 18}, expect: []bool{false, true, true, false}},
+		{input: "9:30 - 17:00", at: []int{9, 10}, expect: []bool{false, true}},
+		{input: "22:00-06:00", at: []int{21, 23, 5, 6}, expect: []bool{false, true, true, false}},
+		// 土曜日と日曜日は就業時間外で, 日をまたぐときは始まった日の曜日で判断する
+		{input: "09:00-18:00", day: 13, at: []int{10}, expect: []bool{false}},
+		{input: "22:00-06:00", day: 13, at: []int{23, 5}, expect: []bool{false, true}},
+		{input: "22:00-06:00", day: 15, at: []int{5}, expect: []bool{false}},
+		{input: "00:00-24:00", at: []int{0, 23}, expect: []bool{true, true}},
+	}
+
+	for _, p := range params {
+		h, err := parseWorkingHours(p.input)
+		if err != nil {
+			t.Fatalf("parseWorkingHours(%q) = %v", p.input, err)
+		}
+		day := p.day
+		if day == 0 {
+			day = 16
+		}
+		for i, hour := range p.at {
+			at := time.Date(2024, 1, day, hour, 0, 0, 0, time.UTC)
+			if actual := h.contains(at); actual != p.expect[i] {
+				t.Errorf("parseWorkingHours(%q).contains(%v) = %v; want %v", p.input, at, actual, p.expect[i])
+			}
+		}
+	}
+
+	for _, s := range []string{"9-17", "09:00-09:00", "09:60-18:00", "25:00-26:00", "09:00-24:30"} {
+		if _, err := parseWorkingHours(s); err == nil {
+			t.Errorf("parseWorkingHours(%q) = nil; want error", s)
+		}
+	}
+}
+
+func TestParseWeekend(t *testing.T) {
+	params := []struct {
+		input  string
+		expect []time.Weekday
+	}{
+		{input: "sat,sun", expect: []time.Weekday{time.Sunday, time.Saturday}},
+		{input: "Friday, Saturday", expect: []time.Weekday{time.Friday, time.Saturday}},
+		{input: "fri-sat", expect: []time.Weekday{time.Friday, time.Saturday}},
+		{input: "sat-mon", expect: []time.Weekday{time.Sunday, time.Monday, time.Saturday}},
+		{input: "fri", expect: []time.Weekday{time.Friday}},
+		{input: "none", expect: nil},
+	}
+
+	for _, p := range params {
+		w, err := parseWeekend(p.input)
+		if err != nil {
+			t.Fatalf("parseWeekend(%q) = %v", p.input, err)
+		}
+		var actual []time.Weekday
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if w.contains(d) {
+				actual = append(actual, d)
+			}
+		}
+		if reflect.DeepEqual(actual, p.expect) == false {
+			t.Errorf("parseWeekend(%q) = %v; want %v", p.input, actual, p.expect)
+		}
+	}
+
+	for _, s := range []string{"", "sat,", "saturn", "fri-", "fri-sat-sun"} {
+		if _, err := parseWeekend(s); err == nil {
+			t.Errorf("parseWeekend(%q) = nil; want error", s)
+		}
+	}
+}
+
+func TestRun_zones(t *testing.T) {
+	nowInterface = &MyTime{}
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if err := os.Mkdir(filepath.Join(dir, "dt"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "[zones]\nteam = [\"Asia/Tokyo\", \"Europe/Berlin\", \"America/Los_Angeles@08:00-17:00\"]\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "dt", ".dt"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	params := []struct {
+		args   []string
+		expect string
+	}{
+		{
+			args: []string{AppName, "--tz", "Asia/Tokyo", "--zones", "team", "2024/01/15 17:00:00"},
+			expect: "Asia/Tokyo           2024/01/15 17:00:00  +09:00  JST\n" +
+				"Europe/Berlin        2024/01/15 09:00:00  +01:00  CET\n" +
+				"America/Los_Angeles  2024/01/15 00:00:00  -08:00  PST    off-hours\n",
+		},
+		{
+			args: []string{AppName, "--zones", "America/Los_Angeles,Asia/Tokyo", "--working-hours", "08:00-20:00", "-o", "15:04", "2024-03-10T17:00:00Z"},
+			expect: "America/Los_Angeles  10:00  -07:00  PDT       off-hours\n" +
+				"Asia/Tokyo           02:00  +09:00  JST  +1d  off-hours\n",
+		},
+		{
+			args: []string{AppName, "--now", "2018-05-12T17:30:00+09:00", "--zones", "UTC,Pacific/Kiritimati", "-o", "relative", "now", "+11h"},
+			expect: "UTC                 in 11 hours  +00:00  UTC       off-hours\n" +
+				"Pacific/Kiritimati  in 11 hours  +14:00  +14  +1d  off-hours\n",
+		},
+		// 土曜日と日曜日は就業時間外
+		{
+			args: []string{AppName, "--zones", "Europe/London,Asia/Tokyo", "-o", "Mon 15:04", "2024-03-08T10:00:00Z", "+1D"},
+			expect: "Europe/London  Sat 10:00  +00:00  GMT    off-hours\n" +
+				"Asia/Tokyo     Sat 19:00  +09:00  JST    off-hours\n",
+		},
+		// 週末はタイムゾーンごとにも --weekend でも指定できる
+		{
+			args: []string{AppName, "--zones", "Europe/London,Asia/Riyadh@09:00-17:00@fri-sat", "-o", "Mon 15:04", "2024-03-10T10:00:00Z"},
+			expect: "Europe/London  Sun 10:00  +00:00  GMT    off-hours\n" +
+				"Asia/Riyadh    Sun 13:00  +03:00  +03\n",
+		},
+		{
+			args: []string{AppName, "--zones", "Europe/London,Asia/Dubai@@sat", "--weekend", "fri,sat", "-o", "Mon 15:04", "2024-03-10T10:00:00Z"},
+			expect: "Europe/London  Sun 10:00  +00:00  GMT\n" +
+				"Asia/Dubai     Sun 14:00  +04:00  +04\n",
+		},
+	}
+	for _, p := range params {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+		if status := clo.Run(p.args); status != ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want %d: %s", p.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != p.expect {
+			t.Errorf("Run(%s): Output = %q; want %q", p.args, outStream.String(), p.expect)
+		}
+	}
+
+	errors := []struct {
+		args   []string
+		expect string
+	}{
+		{args: []string{AppName, "--zones", "Asia/Tokyo,Mars/Olympus", "now"}, expect: "'Mars/Olympus' is invalid time zone."},
+		{args: []string{AppName, "--zones", "UTC", "--working-hours", "9-5", "now"}, expect: "'9-5' is invalid working hours."},
+		{args: []string{AppName, "--zones", "UTC", "1D"}, expect: "--zones needs a date."},
+		{args: []string{AppName, "--zones", "UTC", "--weekend", "weekend", "now"}, expect: "'weekend' is invalid weekend."},
+		{args: []string{AppName, "--zones", "UTC@09:00-17:00@fri+sat", "now"}, expect: "'fri+sat' is invalid weekend."},
+	}
+	for _, p := range errors {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		clo := &CLO{outStream: outStream, errStream: errStream}
+		if status := clo.Run(p.args); status == ExitCodeOK {
+			t.Errorf("Run(%s): ExitStatus = %d; want error", p.args, status)
+		}
+		if strings.Contains(errStream.String(), p.expect) == false {
+			t.Errorf("Run(%s): Output = %v; want %v", p.args, errStream.String(), p.expect)
+		}
+	}
+}